@@ -0,0 +1,337 @@
+package goretry
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pkgRetry "github.com/sethvargo/go-retry"
+)
+
+// randSourceMu guards every RandSource, a source can be shared between concurrent retries
+var randSourceMu sync.Mutex
+
+// lockedSource serializes the access to src with mu
+type lockedSource struct {
+	mu  *sync.Mutex
+	src rand.Source
+}
+
+// Int63 implements rand.Source
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.src.Int63()
+}
+
+// Seed implements rand.Source
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.src.Seed(seed)
+}
+
+type decorrelatedJitterBackoff struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+	base time.Duration
+	cap  time.Duration
+	prev time.Duration
+}
+
+// newDecorrelatedJitter creates a backoff that returns a random delay between base and 3 times the previous delay, capped by cap when greater than zero
+func newDecorrelatedJitter(r *rand.Rand, base, cap time.Duration) pkgRetry.Backoff {
+	if base <= 0 {
+		panic("base must be greater than 0")
+	}
+
+	return &decorrelatedJitterBackoff{
+		rand: r,
+		base: base,
+		cap:  cap,
+		prev: base,
+	}
+}
+
+// Next implements pkgRetry.Backoff
+func (b *decorrelatedJitterBackoff) Next() (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	upper := time.Duration(math.MaxInt64)
+	if b.prev < math.MaxInt64/3 {
+		upper = b.prev * 3
+	}
+
+	next := b.base
+	if upper > b.base {
+		next += time.Duration(b.rand.Int63n(int64(upper - b.base)))
+	}
+	if b.cap > 0 && next > b.cap {
+		next = b.cap
+	}
+
+	b.prev = next
+
+	return next, false
+}
+
+type linearBackoff struct {
+	base    time.Duration
+	attempt uint64
+}
+
+// newLinear creates a backoff that grows the delay by base on every attempt
+func newLinear(base time.Duration) pkgRetry.Backoff {
+	if base <= 0 {
+		panic("base must be greater than 0")
+	}
+
+	return &linearBackoff{
+		base: base,
+	}
+}
+
+// Next implements pkgRetry.Backoff
+func (b *linearBackoff) Next() (time.Duration, bool) {
+	attempt := atomic.AddUint64(&b.attempt, 1)
+	if attempt > uint64(math.MaxInt64/b.base) {
+		return math.MaxInt64, false
+	}
+
+	return b.base * time.Duration(attempt), false
+}
+
+type exponentialBackoff struct {
+	base       time.Duration
+	multiplier float64
+	attempt    uint64
+}
+
+// newExponential creates a backoff that multiplies the delay by multiplier on every attempt
+func newExponential(base time.Duration, multiplier float64) pkgRetry.Backoff {
+	if base <= 0 {
+		panic("base must be greater than 0")
+	}
+
+	return &exponentialBackoff{
+		base:       base,
+		multiplier: multiplier,
+	}
+}
+
+// Next implements pkgRetry.Backoff
+func (b *exponentialBackoff) Next() (time.Duration, bool) {
+	attempt := atomic.AddUint64(&b.attempt, 1)
+
+	next := float64(b.base) * math.Pow(b.multiplier, float64(attempt-1))
+	if next >= math.MaxInt64 {
+		return math.MaxInt64, false
+	}
+
+	return time.Duration(next), false
+}
+
+type customBackoff struct {
+	fn      func(attempt int) time.Duration
+	attempt int64
+}
+
+// newCustomBackoff creates a backoff that returns the delays computed by fn
+func newCustomBackoff(fn func(attempt int) time.Duration) pkgRetry.Backoff {
+	return &customBackoff{
+		fn: fn,
+	}
+}
+
+// Next implements pkgRetry.Backoff
+func (b *customBackoff) Next() (time.Duration, bool) {
+	return b.fn(int(atomic.AddInt64(&b.attempt, 1))), false
+}
+
+type scheduleBackoff struct {
+	delays  []time.Duration
+	attempt int64
+}
+
+// newSchedule creates a backoff that returns the delays in order and stops after the last one
+func newSchedule(delays []time.Duration) pkgRetry.Backoff {
+	return &scheduleBackoff{
+		delays: delays,
+	}
+}
+
+// Next implements pkgRetry.Backoff
+func (b *scheduleBackoff) Next() (time.Duration, bool) {
+	attempt := atomic.AddInt64(&b.attempt, 1)
+	if attempt > int64(len(b.delays)) {
+		return 0, true
+	}
+
+	return b.delays[attempt-1], false
+}
+
+// withJitter adds a random jitter between -j and j to the delay of next, the random values are taken from r.
+// When maxFraction is greater than 0, j is clamped to maxFraction of the delay
+func withJitter(r *rand.Rand, j time.Duration, maxFraction float64, next pkgRetry.Backoff) pkgRetry.Backoff {
+	return pkgRetry.BackoffFunc(func() (time.Duration, bool) {
+		val, stop := next.Next()
+		if stop {
+			return 0, true
+		}
+
+		bound := j
+		if limit := time.Duration(float64(val) * maxFraction); maxFraction > 0 && limit < bound {
+			bound = limit
+		}
+		if bound <= 0 {
+			return val, false
+		}
+
+		return addJitter(r, val, int64(bound)), false
+	})
+}
+
+// withJitterPercent adds a random jitter between -pct% and pct% of the delay of next
+func withJitterPercent(r *rand.Rand, pct float64, next pkgRetry.Backoff) pkgRetry.Backoff {
+	return pkgRetry.BackoffFunc(func() (time.Duration, bool) {
+		val, stop := next.Next()
+		if stop {
+			return 0, true
+		}
+
+		j := int64(math.Min(float64(val)*pct/100, math.MaxInt64/2))
+		if j <= 0 {
+			return val, false
+		}
+
+		return addJitter(r, val, j), false
+	})
+}
+
+// addJitter adds a random value between -bound and bound to val, the result is kept between 0 and math.MaxInt64 instead of overflowing
+func addJitter(r *rand.Rand, val time.Duration, bound int64) time.Duration {
+	if bound > math.MaxInt64/2 {
+		bound = math.MaxInt64 / 2
+	}
+
+	diff := time.Duration(r.Int63n(bound*2) - bound)
+	if diff > 0 && val > math.MaxInt64-diff {
+		return math.MaxInt64
+	}
+
+	val += diff
+	if val < 0 {
+		val = 0
+	}
+
+	return val
+}
+
+// withFullJitter replaces the delay of next with a random delay between 0 and the delay
+func withFullJitter(r *rand.Rand, next pkgRetry.Backoff) pkgRetry.Backoff {
+	return pkgRetry.BackoffFunc(func() (time.Duration, bool) {
+		val, stop := next.Next()
+		if stop {
+			return 0, true
+		}
+		if val <= 0 {
+			return 0, false
+		}
+
+		return time.Duration(r.Int63n(int64(val))), false
+	})
+}
+
+// withEqualJitter replaces the delay of next with a random delay between half the delay and the delay
+func withEqualJitter(r *rand.Rand, next pkgRetry.Backoff) pkgRetry.Backoff {
+	return pkgRetry.BackoffFunc(func() (time.Duration, bool) {
+		val, stop := next.Next()
+		if stop {
+			return 0, true
+		}
+		if val <= 0 {
+			return 0, false
+		}
+
+		half := val / 2
+
+		return val - half + time.Duration(r.Int63n(int64(half)+1)), false
+	})
+}
+
+// withRandomFirstDelay replaces the first delay of next with a random delay between 0 and the delay, the following delays are unchanged
+func withRandomFirstDelay(r *rand.Rand, next pkgRetry.Backoff) pkgRetry.Backoff {
+	var first int32
+
+	return pkgRetry.BackoffFunc(func() (time.Duration, bool) {
+		val, stop := next.Next()
+		if stop {
+			return 0, true
+		}
+		if val <= 0 || !atomic.CompareAndSwapInt32(&first, 0, 1) {
+			return val, false
+		}
+
+		return time.Duration(r.Int63n(int64(val) + 1)), false
+	})
+}
+
+// withMinDelay raises the delay of next to at least floor
+func withMinDelay(floor time.Duration, next pkgRetry.Backoff) pkgRetry.Backoff {
+	return pkgRetry.BackoffFunc(func() (time.Duration, bool) {
+		val, stop := next.Next()
+		if stop {
+			return 0, true
+		}
+
+		if val < floor {
+			val = floor
+		}
+
+		return val, false
+	})
+}
+
+// withMaxDelay caps the delay of next to ceiling, the delays that reach ceiling stay flat
+func withMaxDelay(ceiling time.Duration, next pkgRetry.Backoff) pkgRetry.Backoff {
+	return pkgRetry.BackoffFunc(func() (time.Duration, bool) {
+		val, stop := next.Next()
+		if stop {
+			return 0, true
+		}
+
+		if val > ceiling {
+			val = ceiling
+		}
+
+		return val, false
+	})
+}
+
+// withMaxDuration stops next once timeout has elapsed since its creation, the delay is capped to the remaining time
+func withMaxDuration(clock Clock, timeout time.Duration, next pkgRetry.Backoff) pkgRetry.Backoff {
+	start := clock.Now()
+
+	return pkgRetry.BackoffFunc(func() (time.Duration, bool) {
+		remaining := timeout - clock.Now().Sub(start)
+		if remaining <= 0 {
+			return 0, true
+		}
+
+		val, stop := next.Next()
+		if stop {
+			return 0, true
+		}
+
+		if val > remaining {
+			val = remaining
+		}
+
+		return val, false
+	})
+}
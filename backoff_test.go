@@ -0,0 +1,540 @@
+package goretry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	pkgRetry "github.com/sethvargo/go-retry"
+)
+
+func TestGetRandSharedSource(t *testing.T) {
+	cfg := Config{
+		InitialDelay: time.Millisecond,
+		MaxRetries:   3,
+		Jitter:       time.Millisecond,
+		JitterMode:   JitterAdditive,
+		RandSource:   rand.New(rand.NewSource(1)),
+	}
+	errTest := errors.New("test")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := DoRetry(context.Background(), cfg, func(context.Context) error {
+				return errTest
+			}, []error{errTest})
+			if !errors.Is(err, errTest) {
+				t.Errorf("DoRetry() error = %v, want %v", err, errTest)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRandomBackoffDeterministic(t *testing.T) {
+	delays := func(seed int64) []time.Duration {
+		cfg := Config{
+			InitialDelay: time.Second,
+			BackoffType:  Random,
+			MaxRetries:   4,
+			RandSource:   rand.New(rand.NewSource(seed)),
+		}
+		b := getBackoff(cfg)
+		var out []time.Duration
+		for {
+			next, stop := b.Next()
+			if stop {
+				return out
+			}
+			out = append(out, next)
+		}
+	}
+
+	picked := map[time.Duration]bool{}
+	for seed := int64(0); seed < 10; seed++ {
+		first, second := delays(seed), delays(seed)
+		if len(first) != 4 || !reflect.DeepEqual(first, second) {
+			t.Errorf("seed %d delays = %v and %v, want the same 4 delays", seed, first, second)
+			continue
+		}
+		picked[first[3]] = true
+	}
+	if len(picked) < 2 {
+		t.Errorf("Random picked a single backoff type for 10 seeds")
+	}
+}
+
+func TestJitterOnlyFor(t *testing.T) {
+	delays := func(cfg Config) []time.Duration {
+		b := getBackoff(cfg)
+		var out []time.Duration
+		for i := 0; i < 4; i++ {
+			next, _ := b.Next()
+			out = append(out, next)
+		}
+
+		return out
+	}
+	jittered := func(delays []time.Duration) bool {
+		for _, d := range delays {
+			if d%time.Second != 0 {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	cfg := Config{InitialDelay: time.Second, MaxRetries: 4, Jitter: 500 * time.Millisecond, JitterOnlyFor: []BackoffType{Exponential}}
+	if got := delays(cfg); jittered(got) {
+		t.Errorf("constant delays = %v, want no jitter", got)
+	}
+
+	cfg.BackoffType = Exponential
+	if got := delays(cfg); !jittered(got) {
+		t.Errorf("exponential delays = %v, want jitter", got)
+	}
+
+	cfg.BackoffType = Random
+	for seed := int64(0); seed < 10; seed++ {
+		cfg.RandSource = rand.New(rand.NewSource(seed))
+		picked := []BackoffType{Constant, Exponential, Fibonacci}[rand.New(rand.NewSource(seed)).Intn(3)]
+		if got := delays(cfg); jittered(got) != (picked == Exponential) {
+			t.Errorf("seed %d picked %s, delays = %v, want jitter only for exponential", seed, picked, got)
+		}
+	}
+}
+
+func TestDecorrelatedJitterBounds(t *testing.T) {
+	base, ceiling := 10*time.Millisecond, time.Second
+	b := newDecorrelatedJitter(rand.New(rand.NewSource(1)), base, ceiling)
+
+	prev := base
+	for i := 0; i < 1000; i++ {
+		next, stop := b.Next()
+		if stop {
+			t.Fatalf("Next() stopped at %d", i)
+		}
+
+		upper := prev * 3
+		if upper > ceiling {
+			upper = ceiling
+		}
+		if next < base || next > upper {
+			t.Fatalf("Next() = %s at %d, want between %s and %s", next, i, base, upper)
+		}
+		prev = next
+	}
+}
+
+func TestDecorrelatedJitterConfig(t *testing.T) {
+	cfg := Config{InitialDelay: 10 * time.Millisecond, BackoffType: DecorrelatedJitter, MaxDuration: time.Second, MaxRetries: 50}
+	for _, d := range cfg.PreviewDelays(50) {
+		if d < 0 || d > time.Second {
+			t.Errorf("delay = %s, want between 0 and 1s", d)
+		}
+	}
+}
+
+func TestMaxDelayPlateau(t *testing.T) {
+	cfg := Config{InitialDelay: time.Second, BackoffType: Exponential, MaxDelay: 5 * time.Second, MaxRetries: 6}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second, 5 * time.Second}
+	if got := cfg.PreviewDelays(10); !reflect.DeepEqual(got, want) {
+		t.Errorf("PreviewDelays() = %v, want %v", got, want)
+	}
+}
+
+func TestMaxDelayPlateauWithJitter(t *testing.T) {
+	cfg := Config{InitialDelay: time.Second, BackoffType: Exponential, MaxRetries: 199, MaxDelay: time.Minute, Jitter: 200 * time.Millisecond, RandSource: rand.New(rand.NewSource(1))}
+	b := getBackoff(cfg)
+
+	for i := 1; i <= 199; i++ {
+		next, stop := b.Next()
+		if stop || next <= 0 || next > time.Minute {
+			t.Fatalf("Next() #%d = %s, %t, want a delay up to %s", i, next, stop, time.Minute)
+		}
+		if i > 64 && next != time.Minute {
+			t.Errorf("Next() #%d = %s, want the plateau %s", i, next, time.Minute)
+		}
+	}
+}
+
+func TestLinearBackoff(t *testing.T) {
+	cfg := Config{InitialDelay: time.Second, BackoffType: Linear, MaxRetries: 3}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+	if got := cfg.PreviewDelays(5); !reflect.DeepEqual(got, want) {
+		t.Errorf("PreviewDelays() = %v, want %v", got, want)
+	}
+
+	cfg.MaxDuration = 4 * time.Second
+	want = []time.Duration{time.Second, 2 * time.Second, time.Second}
+	if got := cfg.PreviewDelays(5); !reflect.DeepEqual(got, want) {
+		t.Errorf("PreviewDelays() with MaxDuration = %v, want %v", got, want)
+	}
+
+	cfg = Config{InitialDelay: time.Second, BackoffType: Linear, MaxRetries: 3, Jitter: 100 * time.Millisecond, RandSource: rand.New(rand.NewSource(1))}
+	b := getBackoff(cfg)
+	for i := 1; i <= 3; i++ {
+		next, _ := b.Next()
+		if base := time.Duration(i) * time.Second; next < base-cfg.Jitter || next > base+cfg.Jitter {
+			t.Errorf("Next() = %s, want %s with a jitter of %s", next, base, cfg.Jitter)
+		}
+	}
+}
+
+func TestEmptyBackoffTypeIsConstant(t *testing.T) {
+	cfg := Config{InitialDelay: time.Second, MaxRetries: 3}
+
+	want := []time.Duration{time.Second, time.Second, time.Second}
+	if got := cfg.PreviewDelays(3); !reflect.DeepEqual(got, want) {
+		t.Errorf("PreviewDelays() = %v, want %v", got, want)
+	}
+}
+
+func TestRandSourceReproducible(t *testing.T) {
+	delays := func(cfg Config) []time.Duration {
+		b := getBackoff(cfg)
+		var out []time.Duration
+		for i := 0; i < 10; i++ {
+			next, _ := b.Next()
+			out = append(out, next)
+		}
+
+		return out
+	}
+
+	for _, backoffType := range []BackoffType{Constant, DecorrelatedJitter} {
+		cfg := Config{InitialDelay: time.Second, BackoffType: backoffType, MaxRetries: 10, Jitter: 500 * time.Millisecond}
+
+		cfg.RandSource = rand.New(rand.NewSource(42))
+		first := delays(cfg)
+		cfg.RandSource = rand.New(rand.NewSource(42))
+		if second := delays(cfg); !reflect.DeepEqual(first, second) {
+			t.Errorf("%s delays = %v and %v, want the same delays for the same seed", backoffType, first, second)
+		}
+	}
+}
+
+func TestPreviewDelays(t *testing.T) {
+	s := time.Second
+	tests := []struct {
+		name string
+		cfg  Config
+		want []time.Duration
+	}{
+		{name: "exponential", cfg: Config{InitialDelay: s, BackoffType: Exponential, MaxRetries: 5}, want: []time.Duration{s, 2 * s, 4 * s, 8 * s, 16 * s}},
+		{name: "fibonacci", cfg: Config{InitialDelay: s, BackoffType: Fibonacci, MaxRetries: 5}, want: []time.Duration{s, 2 * s, 3 * s, 5 * s, 8 * s}},
+		{name: "max delay", cfg: Config{InitialDelay: s, BackoffType: Fibonacci, MaxRetries: 5, MaxDelay: 4 * s}, want: []time.Duration{s, 2 * s, 3 * s, 4 * s, 4 * s}},
+		{name: "max duration", cfg: Config{InitialDelay: s, BackoffType: Exponential, MaxRetries: 5, MaxDuration: 10 * s}, want: []time.Duration{s, 2 * s, 4 * s, 3 * s}},
+		{name: "max retries", cfg: Config{InitialDelay: s, MaxRetries: 2}, want: []time.Duration{s, s}},
+		{name: "jitter ignored", cfg: Config{InitialDelay: s, MaxRetries: 2, Jitter: s}, want: []time.Duration{s, s}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.PreviewDelays(5); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PreviewDelays() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := (Config{}).PreviewDelays(3); got != nil {
+		t.Errorf("PreviewDelays() of an invalid config = %v, want nil", got)
+	}
+}
+
+func TestJitterModes(t *testing.T) {
+	d := time.Second
+	delays := func(mode JitterMode) []time.Duration {
+		cfg := Config{InitialDelay: d, Infinite: true, JitterMode: mode, Jitter: 100 * time.Millisecond, RandSource: rand.New(rand.NewSource(1))}
+		b := getBackoff(cfg)
+		out := make([]time.Duration, 1000)
+		for i := range out {
+			out[i], _ = b.Next()
+		}
+
+		return out
+	}
+
+	minFull := d
+	for _, v := range delays(JitterFull) {
+		if v < 0 || v >= d {
+			t.Fatalf("full jitter delay = %s, want between 0 and %s", v, d)
+		}
+		if v < minFull {
+			minFull = v
+		}
+	}
+	if minFull > 10*time.Millisecond {
+		t.Errorf("full jitter smallest delay = %s, want close to 0", minFull)
+	}
+
+	for _, v := range delays(JitterEqual) {
+		if v < d/2 || v > d {
+			t.Fatalf("equal jitter delay = %s, want between %s and %s", v, d/2, d)
+		}
+	}
+
+	for _, v := range delays(JitterAdditive) {
+		if v < 900*time.Millisecond || v > 1100*time.Millisecond {
+			t.Fatalf("additive jitter delay = %s, want %s with a jitter of 100ms", v, d)
+		}
+	}
+
+	for _, v := range delays(JitterNone) {
+		if v != d {
+			t.Fatalf("no jitter delay = %s, want %s", v, d)
+		}
+	}
+}
+
+func TestJitterPercent(t *testing.T) {
+	cfg := Config{InitialDelay: time.Second, BackoffType: Exponential, MaxRetries: 6, JitterPercent: 10, RandSource: rand.New(rand.NewSource(1))}
+	b := getBackoff(cfg)
+
+	base := time.Second
+	var maxDiff time.Duration
+	for i := 0; i < 6; i++ {
+		next, _ := b.Next()
+		if j := base / 10; next < base-j || next > base+j {
+			t.Errorf("Next() = %s, want %s with a jitter of %s", next, base, j)
+		}
+		if diff := (next - base).Abs(); diff > maxDiff {
+			maxDiff = diff
+		}
+		base *= 2
+	}
+	if maxDiff <= 100*time.Millisecond {
+		t.Errorf("largest jitter = %s, want it to grow with the delay", maxDiff)
+	}
+
+	cfg.Jitter = time.Second
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Validate() with Jitter and JitterPercent = %v, want %v", err, ErrInvalidConfig)
+	}
+}
+
+func TestJitterPercentSaturatedDelay(t *testing.T) {
+	cfg := Config{InitialDelay: time.Second, BackoffType: Exponential, MaxRetries: 200, MaxDelay: time.Minute, JitterPercent: 50, RandSource: rand.New(rand.NewSource(1))}
+	b := getBackoff(cfg)
+
+	for i := 1; i <= 200; i++ {
+		next, stop := b.Next()
+		if stop || next <= 0 || next > time.Minute {
+			t.Fatalf("Next() #%d = %s, %t, want a delay up to %s", i, next, stop, time.Minute)
+		}
+		if i > 100 && next != time.Minute {
+			t.Errorf("Next() #%d = %s, want the plateau %s", i, next, time.Minute)
+		}
+	}
+}
+
+func TestExponentialMultiplier(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		multiplier float64
+		want       []time.Duration
+	}{
+		{multiplier: 0, want: []time.Duration{100 * ms, 200 * ms, 400 * ms, 800 * ms}},
+		{multiplier: 1.5, want: []time.Duration{100 * ms, 150 * ms, 225 * ms, 337500 * time.Microsecond}},
+		{multiplier: 3, want: []time.Duration{100 * ms, 300 * ms, 900 * ms, 2700 * ms}},
+	}
+	for _, tt := range tests {
+		cfg := Config{InitialDelay: 100 * ms, BackoffType: Exponential, MaxRetries: 4, Multiplier: tt.multiplier}
+		if got := cfg.PreviewDelays(4); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("PreviewDelays() with multiplier %v = %v, want %v", tt.multiplier, got, tt.want)
+		}
+	}
+
+	if err := (Config{InitialDelay: ms, Multiplier: 0.5}).Validate(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Validate() with multiplier 0.5 = %v, want %v", err, ErrInvalidConfig)
+	}
+}
+
+func TestIdempotencyKeyJitter(t *testing.T) {
+	delays := func(key string) []time.Duration {
+		cfg := Config{InitialDelay: time.Second, MaxRetries: 5, Jitter: 500 * time.Millisecond, IdempotencyKey: key}
+		b := getBackoff(cfg)
+		var out []time.Duration
+		for i := 0; i < 5; i++ {
+			next, _ := b.Next()
+			out = append(out, next)
+		}
+
+		return out
+	}
+
+	first := delays("order-1")
+	if again := delays("order-1"); !reflect.DeepEqual(first, again) {
+		t.Errorf("same key delays = %v and %v, want the same delays", first, again)
+	}
+	if other := delays("order-2"); reflect.DeepEqual(first, other) {
+		t.Errorf("different keys delays = %v, want different delays", first)
+	}
+	if len(map[time.Duration]bool{first[0]: true, first[1]: true, first[2]: true}) == 1 {
+		t.Errorf("delays = %v, want a different jitter per attempt", first)
+	}
+}
+
+func TestCustomBackoff(t *testing.T) {
+	s := time.Second
+	schedule := []time.Duration{s, 2 * s, 5 * s, 30 * s}
+	cfg := Config{
+		BackoffType: Exponential,
+		MaxRetries:  6,
+		CustomBackoff: func(attempt int) time.Duration {
+			if attempt > len(schedule) {
+				return schedule[len(schedule)-1]
+			}
+			return schedule[attempt-1]
+		},
+	}
+
+	want := []time.Duration{s, 2 * s, 5 * s, 30 * s, 30 * s, 30 * s}
+	if got := cfg.PreviewDelays(10); !reflect.DeepEqual(got, want) {
+		t.Errorf("PreviewDelays() = %v, want %v", got, want)
+	}
+
+	cfg.MaxDuration = 10 * s
+	want = []time.Duration{s, 2 * s, 5 * s, 2 * s}
+	if got := cfg.PreviewDelays(10); !reflect.DeepEqual(got, want) {
+		t.Errorf("PreviewDelays() with MaxDuration = %v, want %v", got, want)
+	}
+
+	cfg.MaxDuration = 0
+	cfg.Jitter = 100 * time.Millisecond
+	cfg.RandSource = rand.New(rand.NewSource(1))
+	b := getBackoff(cfg)
+	for i := 0; i < 4; i++ {
+		if next, _ := b.Next(); next < schedule[i]-cfg.Jitter || next > schedule[i]+cfg.Jitter {
+			t.Errorf("Next() = %s, want %s with a jitter of %s", next, schedule[i], cfg.Jitter)
+		}
+	}
+}
+
+func TestJitterMaxFraction(t *testing.T) {
+	cfg := Config{InitialDelay: 10 * time.Millisecond, Infinite: true, Jitter: time.Second, JitterMaxFraction: 0.2, RandSource: rand.New(rand.NewSource(1))}
+	b := getBackoff(cfg)
+
+	for i := 0; i < 1000; i++ {
+		if next, _ := b.Next(); next < 8*time.Millisecond || next > 12*time.Millisecond {
+			t.Fatalf("Next() = %s, want 10ms with a jitter of at most 2ms", next)
+		}
+	}
+
+	for _, fraction := range []float64{-0.1, 1.5} {
+		if err := (Config{InitialDelay: time.Second, JitterMaxFraction: fraction}).Validate(); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("Validate() with JitterMaxFraction %v = %v, want %v", fraction, err, ErrInvalidConfig)
+		}
+	}
+}
+
+func TestMinInterval(t *testing.T) {
+	cfg := Config{InitialDelay: time.Second, Infinite: true, JitterMode: JitterFull, MinInterval: 500 * time.Millisecond, RandSource: rand.New(rand.NewSource(1))}
+	b := getBackoff(cfg)
+
+	floored := 0
+	for i := 0; i < 1000; i++ {
+		next, _ := b.Next()
+		if next < cfg.MinInterval || next > time.Second {
+			t.Fatalf("Next() = %s, want between %s and 1s", next, cfg.MinInterval)
+		}
+		if next == cfg.MinInterval {
+			floored++
+		}
+	}
+	if floored == 0 {
+		t.Errorf("no delay was raised to MinInterval")
+	}
+
+	if err := (Config{InitialDelay: time.Second, MinInterval: 2 * time.Second, MaxDelay: time.Second}).Validate(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Validate() with MinInterval above MaxDelay = %v, want %v", err, ErrInvalidConfig)
+	}
+}
+
+func TestRandomizeFirstDelay(t *testing.T) {
+	firsts := map[time.Duration]bool{}
+	for seed := int64(0); seed < 20; seed++ {
+		cfg := Config{InitialDelay: time.Second, MaxRetries: 3, RandomizeFirstDelay: true, RandSource: rand.New(rand.NewSource(seed))}
+		b := getBackoff(cfg)
+
+		first, _ := b.Next()
+		if first < 0 || first > time.Second {
+			t.Fatalf("first delay = %s, want between 0 and 1s", first)
+		}
+		firsts[first] = true
+
+		for i := 0; i < 2; i++ {
+			if next, _ := b.Next(); next != time.Second {
+				t.Errorf("delay %d = %s, want 1s", i+2, next)
+			}
+		}
+	}
+	if len(firsts) < 10 {
+		t.Errorf("first delays = %v, want them to vary across runs", firsts)
+	}
+}
+
+func TestPreviewBackoff(t *testing.T) {
+	s := time.Second
+	tests := []struct {
+		name string
+		cfg  Config
+		want []time.Duration
+	}{
+		{name: "constant", cfg: Config{InitialDelay: s}, want: []time.Duration{s, s, s, s}},
+		{name: "exponential", cfg: Config{InitialDelay: s, BackoffType: Exponential}, want: []time.Duration{s, 2 * s, 4 * s, 8 * s}},
+		{name: "exponential multiplier", cfg: Config{InitialDelay: s, BackoffType: Exponential, Multiplier: 3}, want: []time.Duration{s, 3 * s, 9 * s, 27 * s}},
+		{name: "fibonacci", cfg: Config{InitialDelay: s, BackoffType: Fibonacci}, want: []time.Duration{s, 2 * s, 3 * s, 5 * s}},
+		{name: "linear", cfg: Config{InitialDelay: s, BackoffType: Linear}, want: []time.Duration{s, 2 * s, 3 * s, 4 * s}},
+		{name: "decorrelated jitter", cfg: Config{InitialDelay: s, BackoffType: DecorrelatedJitter, MaxDuration: 20 * s}, want: []time.Duration{3 * s, 9 * s, 20 * s, 20 * s}},
+		{name: "schedule", cfg: Config{Schedule: []time.Duration{s, 5 * s}}, want: []time.Duration{s, 5 * s}},
+		{name: "limits ignored", cfg: Config{InitialDelay: 2 * s, MaxRetries: 1, MaxDelay: s, Jitter: s}, want: []time.Duration{2 * s, 2 * s, 2 * s, 2 * s}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PreviewBackoff(tt.cfg, 4); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PreviewBackoff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := PreviewBackoff(Config{}, 4); got != nil {
+		t.Errorf("PreviewBackoff() of an invalid config = %v, want nil", got)
+	}
+}
+
+func TestWithMaxDelay(t *testing.T) {
+	s := time.Second
+	b := withMaxDelay(5*s, pkgRetry.BackoffFunc(func() func() (time.Duration, bool) {
+		delays := []time.Duration{0, 10 * s, 3 * s, 0}
+		return func() (time.Duration, bool) {
+			if len(delays) == 0 {
+				return 0, true
+			}
+			d := delays[0]
+			delays = delays[1:]
+			return d, false
+		}
+	}()))
+
+	var got []time.Duration
+	for {
+		next, stop := b.Next()
+		if stop {
+			break
+		}
+		got = append(got, next)
+	}
+	if want := []time.Duration{0, 5 * s, 3 * s, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("withMaxDelay() delays = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package goretry
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// DoRetryBatch will perform a retry like DoRetry for every function in parallel, using at most Concurrency workers.
+// The returned slice holds the error of every function at the same index, IdempotencyKey is suffixed with the index so the workers do not share the same jitter
+func DoRetryBatch(ctx context.Context, cfg Config, fns []func(context.Context) error, retryableError []error) []error {
+	errs := make([]error, len(fns))
+
+	workers := cfg.Concurrency
+	if workers <= 0 || workers > len(fns) {
+		workers = len(fns)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				errs[j] = DoRetry(ctx, indexedConfig(cfg, j), fns[j], retryableError)
+			}
+		}()
+	}
+
+	for i := range fns {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return errs
+}
+
+// DoRetryGroup will perform a retry like DoRetry for every function in parallel, the first error cancels the context of the other functions and is returned.
+// IdempotencyKey is suffixed with the index of the function like DoRetryBatch
+func DoRetryGroup(ctx context.Context, cfg Config, retryableError []error, fns ...func(context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for i, fn := range fns {
+		wg.Add(1)
+		go func(cfg Config, fn func(context.Context) error) {
+			defer wg.Done()
+			if err := DoRetry(ctx, cfg, fn, retryableError); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(indexedConfig(cfg, i), fn)
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+// indexedConfig returns cfg with the index i appended to IdempotencyKey, an empty key is kept
+func indexedConfig(cfg Config, i int) Config {
+	if cfg.IdempotencyKey != "" {
+		cfg.IdempotencyKey += "/" + strconv.Itoa(i)
+	}
+
+	return cfg
+}
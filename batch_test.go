@@ -0,0 +1,141 @@
+package goretry
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIndexedConfigJitter(t *testing.T) {
+	cfg := Config{
+		InitialDelay:   time.Second,
+		MaxRetries:     5,
+		Jitter:         500 * time.Millisecond,
+		IdempotencyKey: "order-42",
+	}
+
+	delays := func(cfg Config) []time.Duration {
+		b := getBackoff(cfg)
+		var out []time.Duration
+		for i := 0; i < 5; i++ {
+			next, _ := b.Next()
+			out = append(out, next)
+		}
+
+		return out
+	}
+
+	first, second := delays(indexedConfig(cfg, 0)), delays(indexedConfig(cfg, 1))
+	if reflect.DeepEqual(first, second) {
+		t.Errorf("workers share the same jitter %v", first)
+	}
+	if again := delays(indexedConfig(cfg, 0)); !reflect.DeepEqual(first, again) {
+		t.Errorf("delays of the same index = %v, want %v", again, first)
+	}
+	if got := indexedConfig(Config{}, 3).IdempotencyKey; got != "" {
+		t.Errorf("indexedConfig() key = %q, want empty", got)
+	}
+}
+
+func TestDoRetryBatch(t *testing.T) {
+	errTest := errors.New("test")
+	cfg := fastConfig(2)
+	cfg.Concurrency = 2
+
+	var running, maxRunning int32
+	track := func(fn func(context.Context) error) func(context.Context) error {
+		return func(ctx context.Context) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+
+			return fn(ctx)
+		}
+	}
+
+	calls := make([]int, 4)
+	fns := []func(context.Context) error{
+		track(failFor(0, nil, &calls[0])),
+		track(failFor(1, errTest, &calls[1])),
+		track(failFor(10, errTest, &calls[2])),
+		track(failFor(10, os.ErrExist, &calls[3])),
+	}
+	errs := DoRetryBatch(context.Background(), cfg, fns, []error{errTest})
+
+	if errs[0] != nil || errs[1] != nil || !errors.Is(errs[2], errTest) || errs[3] != os.ErrExist {
+		t.Errorf("DoRetryBatch() = %v, want nil, nil, %v, %v", errs, errTest, os.ErrExist)
+	}
+	if want := []int{1, 2, 3, 1}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if maxRunning > 2 {
+		t.Errorf("concurrent calls = %d, want at most 2", maxRunning)
+	}
+}
+
+func TestDoRetryBatchCanceled(t *testing.T) {
+	errTest := errors.New("test")
+	cfg := Config{InitialDelay: time.Hour, MaxRetries: 3}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	fns := make([]func(context.Context) error, 3)
+	for i := range fns {
+		fns[i] = func(context.Context) error {
+			return errTest
+		}
+	}
+	for i, err := range DoRetryBatch(ctx, cfg, fns, []error{errTest}) {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("DoRetryBatch()[%d] = %v, want %v", i, err, context.Canceled)
+		}
+	}
+}
+
+func TestDoRetryGroup(t *testing.T) {
+	errTest := errors.New("test")
+	errFatal := errors.New("fatal")
+
+	var calls [2]int
+	ok := func(i int) func(context.Context) error {
+		return failFor(1, errTest, &calls[i])
+	}
+	if err := DoRetryGroup(context.Background(), fastConfig(2), []error{errTest}, ok(0), ok(1)); err != nil || calls != [2]int{2, 2} {
+		t.Errorf("DoRetryGroup() = %v after %v calls, want nil after [2 2]", err, calls)
+	}
+
+	var canceled atomic.Bool
+	started := make(chan struct{})
+	err := DoRetryGroup(context.Background(), fastConfig(2), []error{errTest},
+		func(context.Context) error {
+			return nil
+		},
+		func(context.Context) error {
+			<-started
+			return errFatal
+		},
+		func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			canceled.Store(true)
+			return ctx.Err()
+		},
+	)
+	if err != errFatal {
+		t.Errorf("DoRetryGroup() = %v, want %v", err, errFatal)
+	}
+	if !canceled.Load() {
+		t.Errorf("DoRetryGroup() returned before cancelling the other functions")
+	}
+}
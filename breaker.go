@@ -0,0 +1,41 @@
+package goretry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrCircuitOpen is returned when the circuit breaker does not allow an attempt
+var ErrCircuitOpen = errors.New("goretry: circuit breaker is open")
+
+// CircuitBreaker is consulted before every attempt and records the result of every attempt
+type CircuitBreaker interface {
+	Allow() bool
+	RecordSuccess()
+	RecordFailure()
+}
+
+// DoRetryWithBreaker will perform a retry like DoRetry, the retry stops with ErrCircuitOpen when breaker does not allow the next attempt
+func DoRetryWithBreaker(ctx context.Context, cfg Config, fn func(context.Context) error, retryableError []error, breaker CircuitBreaker) error {
+	var lastErr error
+
+	return DoRetry(ctx, cfg, func(ctx context.Context) error {
+		if !breaker.Allow() {
+			if lastErr != nil {
+				return PermanentError(fmt.Errorf("%w: %w", ErrCircuitOpen, lastErr))
+			}
+			return PermanentError(ErrCircuitOpen)
+		}
+
+		err := fn(ctx)
+		if err != nil {
+			breaker.RecordFailure()
+		} else {
+			breaker.RecordSuccess()
+		}
+		lastErr = err
+
+		return err
+	}, retryableError)
+}
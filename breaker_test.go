@@ -0,0 +1,47 @@
+package goretry
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type fakeBreaker struct {
+	failures  int
+	successes int
+}
+
+func (b *fakeBreaker) Allow() bool {
+	return b.failures < 2
+}
+
+func (b *fakeBreaker) RecordSuccess() {
+	b.successes++
+	b.failures = 0
+}
+
+func (b *fakeBreaker) RecordFailure() {
+	b.failures++
+}
+
+func TestDoRetryWithBreaker(t *testing.T) {
+	errTest := errors.New("test")
+
+	breaker := &fakeBreaker{}
+	calls := 0
+	err := DoRetryWithBreaker(context.Background(), fastConfig(5), failFor(10, errTest, &calls), []error{errTest}, breaker)
+	if !errors.Is(err, ErrCircuitOpen) || !errors.Is(err, errTest) || calls != 2 {
+		t.Errorf("DoRetryWithBreaker() = %v after %d calls, want %v wrapping %v after 2", err, calls, ErrCircuitOpen, errTest)
+	}
+
+	calls = 0
+	if err := DoRetryWithBreaker(context.Background(), fastConfig(5), failFor(0, nil, &calls), []error{errTest}, breaker); !errors.Is(err, ErrCircuitOpen) || calls != 0 {
+		t.Errorf("DoRetryWithBreaker() with an open breaker = %v after %d calls, want %v before any call", err, calls, ErrCircuitOpen)
+	}
+
+	breaker = &fakeBreaker{}
+	calls = 0
+	if err := DoRetryWithBreaker(context.Background(), fastConfig(5), failFor(1, errTest, &calls), []error{errTest}, breaker); err != nil || breaker.successes != 1 {
+		t.Errorf("DoRetryWithBreaker() = %v with %d successes, want nil with 1", err, breaker.successes)
+	}
+}
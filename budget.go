@@ -0,0 +1,77 @@
+package goretry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	pkgRetry "github.com/sethvargo/go-retry"
+)
+
+// RetryBudget is a token bucket shared across retries to limit the global retry rate, every retry consumes a token
+type RetryBudget struct {
+	mu       sync.Mutex
+	tokens   float64
+	capacity float64
+	refill   float64
+	last     time.Time
+}
+
+// NewRetryBudget creates a full RetryBudget holding up to capacity tokens and refilling refillPerSecond tokens every second
+func NewRetryBudget(capacity int, refillPerSecond float64) *RetryBudget {
+	return &RetryBudget{
+		tokens:   float64(capacity),
+		capacity: float64(capacity),
+		refill:   refillPerSecond,
+		last:     time.Now(),
+	}
+}
+
+// Allow consumes a token, it reports false when the budget is empty
+func (b *RetryBudget) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.refill
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+
+	return true
+}
+
+// budgetBackoff takes a token of budget for every retry
+type budgetBackoff struct {
+	pkgRetry.Backoff
+	budget *RetryBudget
+}
+
+func (b budgetBackoff) allowRetry() bool {
+	return b.budget.Allow()
+}
+
+// DoRetryWithBudget will perform a retry like DoRetry, the retry stops with *RetriesExhaustedError when the budget is empty.
+// A token is only taken for a retry that is about to happen, a nil budget does not limit the retries
+func DoRetryWithBudget(ctx context.Context, cfg Config, fn func(context.Context) error, retryableError []error, budget *RetryBudget) error {
+	cfg = resolveConfig(cfg)
+	newBackoff := func(cfg Config) pkgRetry.Backoff {
+		if budget == nil {
+			return getBackoff(cfg)
+		}
+
+		return budgetBackoff{Backoff: getBackoff(cfg), budget: budget}
+	}
+
+	_, _, err := doRetryBackoff(ctx, cfg, newBackoff, noResult(fn), func(err error) bool {
+		return isRetryableError(cfg, err, retryableError)
+	})
+
+	return err
+}
@@ -0,0 +1,64 @@
+package goretry
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDoRetryWithBudget(t *testing.T) {
+	errTest := errors.New("test")
+	budget := NewRetryBudget(3, 0)
+
+	calls := 0
+	err := DoRetryWithBudget(context.Background(), fastConfig(2), failFor(10, errTest, &calls), []error{errTest}, budget)
+	if !errors.Is(err, errTest) || calls != 3 {
+		t.Errorf("DoRetryWithBudget() = %v after %d calls, want %v after 3", err, calls, errTest)
+	}
+
+	calls = 0
+	err = DoRetryWithBudget(context.Background(), fastConfig(2), failFor(10, errTest, &calls), []error{errTest}, budget)
+	var exhausted *RetriesExhaustedError
+	if !errors.As(err, &exhausted) || calls != 2 {
+		t.Errorf("DoRetryWithBudget() = %v after %d calls, want *RetriesExhaustedError after 2", err, calls)
+	}
+
+	for i := 0; i < 2; i++ {
+		calls = 0
+		_ = DoRetryWithBudget(context.Background(), fastConfig(2), failFor(10, errTest, &calls), []error{errTest}, budget)
+		if calls != 1 {
+			t.Errorf("DoRetryWithBudget() with an empty budget = %d calls, want 1", calls)
+		}
+	}
+
+	calls = 0
+	if err := DoRetryWithBudget(context.Background(), fastConfig(2), failFor(0, nil, &calls), []error{errTest}, budget); err != nil || calls != 1 {
+		t.Errorf("DoRetryWithBudget() = %v after %d calls, want nil after 1", err, calls)
+	}
+}
+
+func TestDoRetryWithBudgetVetoed(t *testing.T) {
+	errTest := errors.New("test")
+	budget := NewRetryBudget(3, 0)
+
+	cfg := fastConfig(2)
+	cfg.BeforeRetry = func(context.Context, int, error) bool {
+		return false
+	}
+	for i := 0; i < 3; i++ {
+		calls := 0
+		_ = DoRetryWithBudget(context.Background(), cfg, failFor(10, errTest, &calls), []error{errTest}, budget)
+	}
+
+	calls := 0
+	err := DoRetryWithBudget(context.Background(), fastConfig(2), failFor(10, errTest, &calls), []error{errTest}, budget)
+	if !errors.Is(err, errTest) || calls != 3 {
+		t.Errorf("DoRetryWithBudget() after vetoed retries = %v after %d calls, want %v after 3", err, calls, errTest)
+	}
+
+	calls = 0
+	err = DoRetryWithBudget(context.Background(), fastConfig(2), failFor(10, errTest, &calls), []error{errTest}, nil)
+	if !errors.Is(err, errTest) || calls != 3 {
+		t.Errorf("DoRetryWithBudget() with a nil budget = %v after %d calls, want %v after 3", err, calls, errTest)
+	}
+}
@@ -0,0 +1,295 @@
+package goretry
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// ConfigBuilder builds a Config by chaining the values to be changed
+type ConfigBuilder struct {
+	patch ConfigPatch
+}
+
+// NewConfigBuilder creates an empty ConfigBuilder
+func NewConfigBuilder() *ConfigBuilder {
+	return &ConfigBuilder{}
+}
+
+// WithInitialDelay sets the InitialDelay
+func (b *ConfigBuilder) WithInitialDelay(d time.Duration) *ConfigBuilder {
+	b.patch.InitialDelay = &d
+	return b
+}
+
+// WithMaxRetries sets the MaxRetries
+func (b *ConfigBuilder) WithMaxRetries(n int) *ConfigBuilder {
+	b.patch.MaxRetries = &n
+	return b
+}
+
+// WithBackoff sets the BackoffType
+func (b *ConfigBuilder) WithBackoff(t BackoffType) *ConfigBuilder {
+	b.patch.BackoffType = &t
+	return b
+}
+
+// WithJitter sets the Jitter
+func (b *ConfigBuilder) WithJitter(d time.Duration) *ConfigBuilder {
+	b.patch.Jitter = &d
+	return b
+}
+
+// WithJitterMode sets the JitterMode
+func (b *ConfigBuilder) WithJitterMode(m JitterMode) *ConfigBuilder {
+	b.patch.JitterMode = &m
+	return b
+}
+
+// WithJitterPercent sets the JitterPercent
+func (b *ConfigBuilder) WithJitterPercent(pct float64) *ConfigBuilder {
+	b.patch.JitterPercent = &pct
+	return b
+}
+
+// WithMaxDuration sets the MaxDuration
+func (b *ConfigBuilder) WithMaxDuration(d time.Duration) *ConfigBuilder {
+	b.patch.MaxDuration = &d
+	return b
+}
+
+// WithMaxDelay sets the MaxDelay
+func (b *ConfigBuilder) WithMaxDelay(d time.Duration) *ConfigBuilder {
+	b.patch.MaxDelay = &d
+	return b
+}
+
+// WithFirstAttemptDelay sets the FirstAttemptDelay
+func (b *ConfigBuilder) WithFirstAttemptDelay(d time.Duration) *ConfigBuilder {
+	b.patch.FirstAttemptDelay = &d
+	return b
+}
+
+// WithAttemptTimeout sets the AttemptTimeout
+func (b *ConfigBuilder) WithAttemptTimeout(d time.Duration) *ConfigBuilder {
+	b.patch.AttemptTimeout = &d
+	return b
+}
+
+// WithMatchByMessage sets the MatchByMessage
+func (b *ConfigBuilder) WithMatchByMessage(v bool) *ConfigBuilder {
+	b.patch.MatchByMessage = &v
+	return b
+}
+
+// WithRecoverPanics sets the RecoverPanics
+func (b *ConfigBuilder) WithRecoverPanics(v bool) *ConfigBuilder {
+	b.patch.RecoverPanics = &v
+	return b
+}
+
+// WithRepanicOnGiveUp sets the RepanicOnGiveUp
+func (b *ConfigBuilder) WithRepanicOnGiveUp(v bool) *ConfigBuilder {
+	b.patch.RepanicOnGiveUp = &v
+	return b
+}
+
+// WithMultiplier sets the Multiplier
+func (b *ConfigBuilder) WithMultiplier(m float64) *ConfigBuilder {
+	b.patch.Multiplier = &m
+	return b
+}
+
+// WithUseContextDeadline sets the UseContextDeadline
+func (b *ConfigBuilder) WithUseContextDeadline(v bool) *ConfigBuilder {
+	b.patch.UseContextDeadline = &v
+	return b
+}
+
+// WithDisabled sets the Disabled
+func (b *ConfigBuilder) WithDisabled(v bool) *ConfigBuilder {
+	b.patch.Disabled = &v
+	return b
+}
+
+// WithIdempotencyKey sets the IdempotencyKey
+func (b *ConfigBuilder) WithIdempotencyKey(key string) *ConfigBuilder {
+	b.patch.IdempotencyKey = &key
+	return b
+}
+
+// WithMaxAttempts sets the MaxAttempts
+func (b *ConfigBuilder) WithMaxAttempts(n int) *ConfigBuilder {
+	b.patch.MaxAttempts = &n
+	return b
+}
+
+// WithJoinErrors sets the JoinErrors
+func (b *ConfigBuilder) WithJoinErrors(v bool) *ConfigBuilder {
+	b.patch.JoinErrors = &v
+	return b
+}
+
+// WithRetryTemporary sets whether temporary errors are retried
+func (b *ConfigBuilder) WithRetryTemporary(retryTemporary bool) *ConfigBuilder {
+	b.patch.RetryTemporary = &retryTemporary
+	return b
+}
+
+// WithJitterMaxFraction sets the maximum fraction of the delay used by the additive jitter
+func (b *ConfigBuilder) WithJitterMaxFraction(fraction float64) *ConfigBuilder {
+	b.patch.JitterMaxFraction = &fraction
+	return b
+}
+
+// WithConcurrency sets the number of functions retried in parallel by DoRetryBatch
+func (b *ConfigBuilder) WithConcurrency(concurrency int) *ConfigBuilder {
+	b.patch.Concurrency = &concurrency
+	return b
+}
+
+// WithMinInterval sets the minimum delay between attempts
+func (b *ConfigBuilder) WithMinInterval(d time.Duration) *ConfigBuilder {
+	b.patch.MinInterval = &d
+	return b
+}
+
+// WithReturnSuccessError sets whether the matched error of SuccessErrors is returned instead of nil
+func (b *ConfigBuilder) WithReturnSuccessError(returnSuccessError bool) *ConfigBuilder {
+	b.patch.ReturnSuccessError = &returnSuccessError
+	return b
+}
+
+// WithRandomizeFirstDelay sets whether the delay before the first retry is randomized
+func (b *ConfigBuilder) WithRandomizeFirstDelay(randomize bool) *ConfigBuilder {
+	b.patch.RandomizeFirstDelay = &randomize
+	return b
+}
+
+// WithRetryOnlyListed sets whether only the listed retryable errors are retried
+func (b *ConfigBuilder) WithRetryOnlyListed(retryOnlyListed bool) *ConfigBuilder {
+	b.patch.RetryOnlyListed = &retryOnlyListed
+	return b
+}
+
+// WithAbsoluteMaxCalls sets the maximum number of fn invocations
+func (b *ConfigBuilder) WithAbsoluteMaxCalls(n int) *ConfigBuilder {
+	b.patch.AbsoluteMaxCalls = &n
+	return b
+}
+
+// WithInfinite sets whether the retries are unlimited
+func (b *ConfigBuilder) WithInfinite(infinite bool) *ConfigBuilder {
+	b.patch.Infinite = &infinite
+	return b
+}
+
+// WithEscalateOnRepeat sets whether the delay escalates when the same error repeats
+func (b *ConfigBuilder) WithEscalateOnRepeat(escalate bool) *ConfigBuilder {
+	b.patch.EscalateOnRepeat = &escalate
+	return b
+}
+
+// WithOnRetry sets the OnRetry callback
+func (b *ConfigBuilder) WithOnRetry(fn func(attempt int, err error, nextDelay time.Duration)) *ConfigBuilder {
+	b.patch.OnRetry = fn
+	return b
+}
+
+// WithOnSuccess sets the OnSuccess callback
+func (b *ConfigBuilder) WithOnSuccess(fn func(attempts int, totalElapsed time.Duration)) *ConfigBuilder {
+	b.patch.OnSuccess = fn
+	return b
+}
+
+// WithOnGiveUp sets the OnGiveUp callback
+func (b *ConfigBuilder) WithOnGiveUp(fn func(attempts int, lastErr error)) *ConfigBuilder {
+	b.patch.OnGiveUp = fn
+	return b
+}
+
+// WithRandSource sets the RandSource
+func (b *ConfigBuilder) WithRandSource(r *rand.Rand) *ConfigBuilder {
+	b.patch.RandSource = r
+	return b
+}
+
+// WithLogger sets the Logger
+func (b *ConfigBuilder) WithLogger(l Logger) *ConfigBuilder {
+	b.patch.Logger = l
+	return b
+}
+
+// WithTraceHook sets the TraceHook
+func (b *ConfigBuilder) WithTraceHook(h TraceHook) *ConfigBuilder {
+	b.patch.TraceHook = h
+	return b
+}
+
+// WithMetricsHook sets the MetricsHook
+func (b *ConfigBuilder) WithMetricsHook(h MetricsHook) *ConfigBuilder {
+	b.patch.MetricsHook = h
+	return b
+}
+
+// WithClock sets the Clock
+func (b *ConfigBuilder) WithClock(c Clock) *ConfigBuilder {
+	b.patch.Clock = c
+	return b
+}
+
+// WithCustomBackoff sets the CustomBackoff
+func (b *ConfigBuilder) WithCustomBackoff(fn func(attempt int) time.Duration) *ConfigBuilder {
+	b.patch.CustomBackoff = fn
+	return b
+}
+
+// WithSuccessErrors sets the errors treated as success
+func (b *ConfigBuilder) WithSuccessErrors(errs ...error) *ConfigBuilder {
+	b.patch.SuccessErrors = errs
+	return b
+}
+
+// WithSchedule sets the delays before every retry
+func (b *ConfigBuilder) WithSchedule(delays ...time.Duration) *ConfigBuilder {
+	b.patch.Schedule = delays
+	return b
+}
+
+// WithDelayFunc sets the DelayFunc callback
+func (b *ConfigBuilder) WithDelayFunc(fn func(attempt int, computed time.Duration) time.Duration) *ConfigBuilder {
+	b.patch.DelayFunc = fn
+	return b
+}
+
+// WithBeforeRetry sets the BeforeRetry callback
+func (b *ConfigBuilder) WithBeforeRetry(fn func(ctx context.Context, attempt int, err error) bool) *ConfigBuilder {
+	b.patch.BeforeRetry = fn
+	return b
+}
+
+// WithOnFlaky sets the OnFlaky callback
+func (b *ConfigBuilder) WithOnFlaky(fn func(attempts int, errs []error)) *ConfigBuilder {
+	b.patch.OnFlaky = fn
+	return b
+}
+
+// WithJitterOnlyFor sets the backoff types the jitter is applied to
+func (b *ConfigBuilder) WithJitterOnlyFor(types ...BackoffType) *ConfigBuilder {
+	b.patch.JitterOnlyFor = types
+	return b
+}
+
+// WithAdaptiveFunc sets the AdaptiveFunc callback
+func (b *ConfigBuilder) WithAdaptiveFunc(fn func(lastLatency time.Duration) time.Duration) *ConfigBuilder {
+	b.patch.AdaptiveFunc = fn
+	return b
+}
+
+// Build returns the Config, values that are not set are taken from DefaultConfig
+func (b *ConfigBuilder) Build() Config {
+	cfg := DefaultConfig()
+	cfg.Apply(b.patch)
+
+	return cfg
+}
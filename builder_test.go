@@ -0,0 +1,25 @@
+package goretry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfigBuilder(t *testing.T) {
+	cfg := NewConfigBuilder().WithBackoff(Exponential).WithMaxRetries(5).WithJitter(time.Second).Build()
+
+	want := DefaultConfig()
+	want.BackoffType = Exponential
+	want.MaxRetries = 5
+	want.Jitter = time.Second
+	if cfg.BackoffType != want.BackoffType || cfg.MaxRetries != want.MaxRetries || cfg.Jitter != want.Jitter {
+		t.Errorf("Build() = %+v, want the values set by the builder", cfg)
+	}
+	if cfg.InitialDelay != want.InitialDelay || cfg.MaxDuration != want.MaxDuration || cfg.Multiplier != want.Multiplier {
+		t.Errorf("Build() = %+v, want the defaults for the fields not set", cfg)
+	}
+
+	if cfg := NewConfigBuilder().WithMaxRetries(0).Build(); cfg.MaxRetries != 0 {
+		t.Errorf("Build() MaxRetries = %d, want 0", cfg.MaxRetries)
+	}
+}
@@ -0,0 +1,44 @@
+package goretry
+
+import (
+	"context"
+	"fmt"
+)
+
+// Cache stores the last successful value of the retries using the same IdempotencyKey
+type Cache[T any] interface {
+	Get(key string) (T, bool)
+	Set(key string, value T)
+}
+
+/*
+DoRetryCached will perform a retry like DoRetryWithResult, a successful value is stored in cache under IdempotencyKey
+
+Notes:
+  - When fn fails with a retryable error and cache holds a value for IdempotencyKey, the cached value is returned instead of retrying
+  - Non retryable errors are returned without looking at the cache
+  - IdempotencyKey must be set, otherwise an error wrapping ErrInvalidConfig is returned
+*/
+func DoRetryCached[T any](ctx context.Context, cfg Config, fn func(context.Context) (T, error), retryableError []error, cache Cache[T]) (T, error) {
+	cfg = resolveConfig(cfg)
+	if cfg.IdempotencyKey == "" {
+		var zero T
+		return zero, fmt.Errorf("%w: IdempotencyKey is required by DoRetryCached", ErrInvalidConfig)
+	}
+
+	v, err := DoRetryWithResult(ctx, cfg, func(ctx context.Context) (T, error) {
+		v, err := fn(ctx)
+		if err != nil && (IsRetryable(err) || isRetryableError(cfg, err, retryableError)) {
+			if cached, ok := cache.Get(cfg.IdempotencyKey); ok {
+				return cached, nil
+			}
+		}
+
+		return v, err
+	}, retryableError)
+	if err == nil {
+		cache.Set(cfg.IdempotencyKey, v)
+	}
+
+	return v, err
+}
@@ -0,0 +1,64 @@
+package goretry
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type mapCache map[string]int
+
+func (c mapCache) Get(key string) (int, bool) {
+	v, ok := c[key]
+	return v, ok
+}
+
+func (c mapCache) Set(key string, value int) {
+	c[key] = value
+}
+
+func TestDoRetryCached(t *testing.T) {
+	errTest := errors.New("test")
+	errFatal := errors.New("fatal")
+
+	tests := []struct {
+		name      string
+		cache     mapCache
+		err       error
+		want      int
+		wantErr   error
+		wantCalls int
+		wantCache int
+	}{
+		{name: "cache hit", cache: mapCache{"key": 7}, err: errTest, want: 7, wantCalls: 1, wantCache: 7},
+		{name: "cache miss", cache: mapCache{}, err: errTest, want: 42, wantCalls: 2, wantCache: 42},
+		{name: "non retryable", cache: mapCache{"key": 7}, err: errFatal, wantErr: errFatal, wantCalls: 1, wantCache: 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := fastConfig(3)
+			cfg.IdempotencyKey = "key"
+			calls := 0
+			got, err := DoRetryCached(context.Background(), cfg, func(context.Context) (int, error) {
+				calls++
+				if calls == 1 {
+					return 0, tt.err
+				}
+				return 42, nil
+			}, []error{errTest}, tt.cache)
+			if got != tt.want || err != tt.wantErr || calls != tt.wantCalls {
+				t.Errorf("DoRetryCached() = %d, %v after %d calls, want %d, %v after %d", got, err, calls, tt.want, tt.wantErr, tt.wantCalls)
+			}
+			if tt.cache["key"] != tt.wantCache {
+				t.Errorf("cache = %v, want %d under key", tt.cache, tt.wantCache)
+			}
+		})
+	}
+
+	_, err := DoRetryCached(context.Background(), fastConfig(3), func(context.Context) (int, error) {
+		return 0, nil
+	}, nil, mapCache{})
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("DoRetryCached() without IdempotencyKey = %v, want %v", err, ErrInvalidConfig)
+	}
+}
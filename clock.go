@@ -0,0 +1,41 @@
+package goretry
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is used to read the time and to wait between attempts, a fake clock can be used to run retries without real sleeping
+type Clock interface {
+	Now() time.Time
+
+	// Sleep waits for d, it returns the context error when ctx is done before
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// getClock returns the configured clock or the real clock when it is not set
+func getClock(cfg Config) Clock {
+	if cfg.Clock == nil {
+		return realClock{}
+	}
+
+	return cfg.Clock
+}
@@ -0,0 +1,29 @@
+package goretry
+
+import "context"
+
+type attemptKey struct{}
+
+// attemptInfo is the attempt carried by the context passed to fn
+type attemptInfo struct {
+	attempt int
+	last    bool
+}
+
+// withAttempt returns a copy of ctx carrying the current attempt number and whether it is the last attempt
+func withAttempt(ctx context.Context, attempt int, last bool) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attemptInfo{attempt: attempt, last: last})
+}
+
+// AttemptFromContext returns the current attempt number starting from 1, it returns 0 when ctx is not passed by a retry
+func AttemptFromContext(ctx context.Context) int {
+	info, _ := ctx.Value(attemptKey{}).(attemptInfo)
+	return info.attempt
+}
+
+// IsLastAttempt reports whether the current attempt is the last one allowed by MaxRetries, MaxAttempts, Schedule or AbsoluteMaxCalls.
+// An attempt stopped by MaxDuration, the context deadline or a non retryable error is not known in advance and reports false
+func IsLastAttempt(ctx context.Context) bool {
+	info, _ := ctx.Value(attemptKey{}).(attemptInfo)
+	return info.last
+}
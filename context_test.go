@@ -0,0 +1,63 @@
+package goretry
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/harlesbayu/go-retry/goretrytest"
+)
+
+func TestAttemptFromContext(t *testing.T) {
+	errTest := errors.New("test")
+
+	var got []int
+	_ = DoRetry(context.Background(), fastConfig(3), func(ctx context.Context) error {
+		got = append(got, AttemptFromContext(ctx))
+		return errTest
+	}, []error{errTest})
+
+	if want := []int{1, 2, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("attempts = %v, want %v", got, want)
+	}
+	if got := AttemptFromContext(context.Background()); got != 0 {
+		t.Errorf("AttemptFromContext() outside a retry = %d, want 0", got)
+	}
+}
+
+func TestIsLastAttempt(t *testing.T) {
+	errTest := errors.New("test")
+	s := time.Second
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want []bool
+	}{
+		{name: "max retries", cfg: fastConfig(3), want: []bool{false, false, false, true}},
+		{name: "max attempts", cfg: Config{InitialDelay: s, MaxAttempts: 2}, want: []bool{false, true}},
+		{name: "schedule", cfg: Config{Schedule: []time.Duration{s, s}}, want: []bool{false, false, true}},
+		{name: "schedule shorter than max attempts", cfg: Config{Schedule: []time.Duration{s, s}, MaxAttempts: 5}, want: []bool{false, false, true}},
+		{name: "max attempts shorter than schedule", cfg: Config{Schedule: []time.Duration{s, s, s}, MaxAttempts: 2}, want: []bool{false, true}},
+		{name: "absolute max calls", cfg: Config{InitialDelay: s, Infinite: true, AbsoluteMaxCalls: 3}, want: []bool{false, false, true}},
+		{name: "single attempt", cfg: Config{InitialDelay: s, MaxAttempts: 1}, want: []bool{true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Clock = goretrytest.NewFakeClock(time.Now())
+			var got []bool
+			_ = DoRetry(context.Background(), tt.cfg, func(ctx context.Context) error {
+				got = append(got, IsLastAttempt(ctx))
+				return errTest
+			}, []error{errTest})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("IsLastAttempt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if IsLastAttempt(context.Background()) {
+		t.Errorf("IsLastAttempt() outside a retry = true, want false")
+	}
+}
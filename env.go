@@ -0,0 +1,96 @@
+package goretry
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+ConfigFromEnv loads the configuration from the environment variables named with prefix, e.g. "RETRY_MAX_RETRIES" for the prefix "RETRY"
+  - MAX_RETRIES, MAX_ATTEMPTS: integers
+  - INITIAL_DELAY, JITTER, MAX_DURATION, MAX_DELAY: durations, e.g. "3s" or "200ms"
+  - BACKOFF_TYPE, JITTER_MODE: names, e.g. "exponential" or "full"
+  - JITTER_PERCENT, MULTIPLIER: numbers
+
+Notes:
+  - Unset variables keep the value of DefaultConfig
+  - An invalid value returns an error wrapping ErrInvalidConfig
+*/
+func ConfigFromEnv(prefix string) (Config, error) {
+	if prefix != "" && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+
+	cfg := DefaultConfig()
+	for _, v := range []struct {
+		name  string
+		parse func(string) error
+	}{
+		{"MAX_RETRIES", intEnv(&cfg.MaxRetries)},
+		{"MAX_ATTEMPTS", intEnv(&cfg.MaxAttempts)},
+		{"INITIAL_DELAY", durationEnv(&cfg.InitialDelay)},
+		{"JITTER", durationEnv(&cfg.Jitter)},
+		{"MAX_DURATION", durationEnv(&cfg.MaxDuration)},
+		{"MAX_DELAY", durationEnv(&cfg.MaxDelay)},
+		{"BACKOFF_TYPE", func(s string) error { cfg.BackoffType = BackoffType(s); return nil }},
+		{"JITTER_MODE", func(s string) error { cfg.JitterMode = JitterMode(s); return nil }},
+		{"JITTER_PERCENT", floatEnv(&cfg.JitterPercent)},
+		{"MULTIPLIER", floatEnv(&cfg.Multiplier)},
+	} {
+		s, ok := os.LookupEnv(prefix + v.name)
+		if !ok {
+			continue
+		}
+		if err := v.parse(s); err != nil {
+			return Config{}, fmt.Errorf("%w: %s%s: %v", ErrInvalidConfig, prefix, v.name, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// intEnv returns a parser storing an integer in dst
+func intEnv(dst *int) func(string) error {
+	return func(s string) error {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+
+		*dst = v
+		return nil
+	}
+}
+
+// durationEnv returns a parser storing a duration in dst
+func durationEnv(dst *time.Duration) func(string) error {
+	return func(s string) error {
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+
+		*dst = v
+		return nil
+	}
+}
+
+// floatEnv returns a parser storing a number in dst
+func floatEnv(dst *float64) func(string) error {
+	return func(s string) error {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+
+		*dst = v
+		return nil
+	}
+}
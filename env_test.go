@@ -0,0 +1,49 @@
+package goretry
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("RETRY_MAX_RETRIES", "5")
+	t.Setenv("RETRY_BACKOFF_TYPE", "exponential")
+	t.Setenv("RETRY_INITIAL_DELAY", "250ms")
+	t.Setenv("RETRY_MULTIPLIER", "1.5")
+
+	cfg, err := ConfigFromEnv("RETRY")
+	if err != nil {
+		t.Fatalf("ConfigFromEnv() error = %v", err)
+	}
+	if cfg.MaxRetries != 5 || cfg.BackoffType != Exponential || cfg.InitialDelay != 250*time.Millisecond || cfg.Multiplier != 1.5 {
+		t.Errorf("ConfigFromEnv() = %+v, want the values of the environment", cfg)
+	}
+	if want := DefaultConfig(); cfg.Jitter != want.Jitter || cfg.MaxDuration != want.MaxDuration {
+		t.Errorf("ConfigFromEnv() = %+v, want the defaults for the unset variables", cfg)
+	}
+
+	if cfg, err := ConfigFromEnv("UNSET_"); err != nil || cfg.MaxRetries != maxRetries || cfg.InitialDelay != initialDelay {
+		t.Errorf("ConfigFromEnv() without variables = %+v, %v, want DefaultConfig", cfg, err)
+	}
+}
+
+func TestConfigFromEnvInvalid(t *testing.T) {
+	tests := []struct {
+		name, value string
+	}{
+		{"MAX_RETRIES", "three"},
+		{"INITIAL_DELAY", "3"},
+		{"BACKOFF_TYPE", "quadratic"},
+		{"JITTER_PERCENT", "ten"},
+		{"MAX_DURATION", "-1s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("RETRY_"+tt.name, tt.value)
+			if _, err := ConfigFromEnv("RETRY"); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("ConfigFromEnv() with %s=%q = %v, want %v", tt.name, tt.value, err, ErrInvalidConfig)
+			}
+		})
+	}
+}
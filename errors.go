@@ -0,0 +1,140 @@
+package goretry
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidConfig is returned when the configuration contains invalid values
+var ErrInvalidConfig = errors.New("goretry: invalid config")
+
+// ErrPanic is matched by *PanicError, add it to the retryable errors to retry a recovered panic
+var ErrPanic = errors.New("goretry: panic recovered")
+
+// RetriesExhaustedError is returned when fn keeps failing with a retryable error until the backoff stops
+type RetriesExhaustedError struct {
+	Attempts int
+	Elapsed  time.Duration
+	Err      error
+}
+
+func (e *RetriesExhaustedError) Error() string {
+	return fmt.Sprintf("goretry: retries exhausted after %d attempts: %v", e.Attempts, e.Err)
+}
+
+// Unwrap returns the last error returned by fn
+func (e *RetriesExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+// MaxDurationExceededError is returned when fn keeps failing with a retryable error until MaxDuration stops the backoff
+type MaxDurationExceededError struct {
+	Attempts    int
+	MaxDuration time.Duration
+	Elapsed     time.Duration
+	Err         error
+}
+
+func (e *MaxDurationExceededError) Error() string {
+	return fmt.Sprintf("goretry: max duration %s exceeded after %d attempts in %s: %v", e.MaxDuration, e.Attempts, e.Elapsed, e.Err)
+}
+
+// Unwrap returns the last error returned by fn
+func (e *MaxDurationExceededError) Unwrap() error {
+	return e.Err
+}
+
+// ErrResultRejected is returned by DoRetryResultIf when the retry stops on a result rejected by shouldRetry
+var ErrResultRejected = errors.New("goretry: result rejected")
+
+// ErrStopped is returned when the stop channel of DoRetryWithStop is closed
+var ErrStopped = errors.New("goretry: retry stopped")
+
+// ContextError is returned when the context is done before the retry finishes, Err is the cause of the context
+type ContextError struct {
+	Attempts int
+	Err      error
+	LastErr  error
+}
+
+func (e *ContextError) Error() string {
+	if e.LastErr == nil {
+		return fmt.Sprintf("goretry: context done after %d attempts: %v", e.Attempts, e.Err)
+	}
+
+	return fmt.Sprintf("goretry: context done after %d attempts: %v: last error: %v", e.Attempts, e.Err, e.LastErr)
+}
+
+// Unwrap returns the context error and the last error returned by fn
+func (e *ContextError) Unwrap() []error {
+	if e.LastErr == nil {
+		return []error{e.Err}
+	}
+
+	return []error{e.Err, e.LastErr}
+}
+
+// RetryAfterError is an error suggesting the minimum delay before the next attempt, e.g. from a Retry-After header.
+// The suggestion is capped by MaxDelay, a suggestion longer than the time left in MaxDuration stops the retry
+type RetryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+type permanentError struct {
+	err error
+}
+
+// PermanentError marks an error as permanent, the retry stops immediately and the error is returned unwrapped
+func PermanentError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+func (e *permanentError) Error() string {
+	return "permanent: " + e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+type resetError struct {
+	err error
+}
+
+// ResetBackoff marks an error as retryable and restarts the backoff, the next delay starts again from the initial delay
+func ResetBackoff(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &resetError{err: err}
+}
+
+func (e *resetError) Error() string {
+	return "reset backoff: " + e.err.Error()
+}
+
+func (e *resetError) Unwrap() error {
+	return e.err
+}
+
+// PanicError is returned when fn panics and RecoverPanics is enabled
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("goretry: panic recovered: %v", e.Value)
+}
+
+// Is reports whether target is ErrPanic
+func (e *PanicError) Is(target error) bool {
+	return target == ErrPanic
+}
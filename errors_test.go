@@ -0,0 +1,295 @@
+package goretry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/harlesbayu/go-retry/goretrytest"
+)
+
+func TestRetriesExhaustedError(t *testing.T) {
+	errTest := errors.New("test")
+
+	calls := 0
+	err := DoRetry(context.Background(), fastConfig(2), failFor(10, errTest, &calls), []error{errTest})
+
+	var exhausted *RetriesExhaustedError
+	if !errors.As(err, &exhausted) {
+		t.Fatalf("DoRetry() = %v, want *RetriesExhaustedError", err)
+	}
+	if !errors.Is(err, errTest) {
+		t.Errorf("DoRetry() = %v, want it to wrap %v", err, errTest)
+	}
+	if exhausted.Attempts != 3 || exhausted.Err != errTest {
+		t.Errorf("RetriesExhaustedError = %+v, want 3 attempts and %v", exhausted, errTest)
+	}
+}
+
+func TestContextError(t *testing.T) {
+	errTest := errors.New("test")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := 0
+	err := DoRetry(ctx, fastConfig(5), func(context.Context) error {
+		calls++
+		if calls == 2 {
+			cancel()
+		}
+		return errTest
+	}, []error{errTest})
+
+	var ctxErr *ContextError
+	if !errors.As(err, &ctxErr) {
+		t.Fatalf("DoRetry() = %v, want *ContextError", err)
+	}
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, errTest) {
+		t.Errorf("DoRetry() = %v, want it to wrap %v and %v", err, context.Canceled, errTest)
+	}
+	if ctxErr.Attempts != 2 || calls != 2 {
+		t.Errorf("ContextError attempts = %d after %d calls, want 2", ctxErr.Attempts, calls)
+	}
+}
+
+func TestContextErrorDeadline(t *testing.T) {
+	errTest := errors.New("test")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	err := DoRetry(ctx, fastConfig(5), func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return errTest
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}, []error{errTest})
+	var ctxErr *ContextError
+	if !errors.As(err, &ctxErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DoRetry() = %v, want *ContextError wrapping %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestPermanentError(t *testing.T) {
+	errTest := errors.New("test")
+	errFatal := errors.New("fatal")
+
+	fn := func(calls *int) func(context.Context) error {
+		return func(context.Context) error {
+			*calls++
+			if *calls == 2 {
+				return PermanentError(errFatal)
+			}
+			return errTest
+		}
+	}
+
+	calls := 0
+	if err := DoRetry(context.Background(), fastConfig(5), fn(&calls), []error{errTest, errFatal}); err != errFatal || calls != 2 {
+		t.Errorf("DoRetry() = %v after %d calls, want %v after 2", err, calls, errFatal)
+	}
+
+	calls = 0
+	if err := DoRetryIf(context.Background(), fastConfig(5), fn(&calls), nil); err != errFatal || calls != 2 {
+		t.Errorf("DoRetryIf() = %v after %d calls, want %v after 2", err, calls, errFatal)
+	}
+
+	if err := PermanentError(nil); err != nil {
+		t.Errorf("PermanentError(nil) = %v, want nil", err)
+	}
+}
+
+func TestRecoverPanics(t *testing.T) {
+	panicking := func(n int, calls *int) func(context.Context) error {
+		return func(context.Context) error {
+			*calls++
+			if *calls <= n {
+				panic("boom")
+			}
+			return nil
+		}
+	}
+
+	cfg := fastConfig(3)
+	cfg.RecoverPanics = true
+
+	calls := 0
+	if err := DoRetry(context.Background(), cfg, panicking(2, &calls), []error{ErrPanic}); err != nil || calls != 3 {
+		t.Errorf("DoRetry() = %v after %d calls, want nil after 3", err, calls)
+	}
+
+	calls = 0
+	err := DoRetry(context.Background(), cfg, panicking(10, &calls), []error{ErrPanic})
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+		t.Errorf("DoRetry() = %v, want *PanicError with the value and the stack", err)
+	}
+
+	calls = 0
+	if err := DoRetry(context.Background(), cfg, panicking(10, &calls), nil); !errors.As(err, &panicErr) || calls != 1 {
+		t.Errorf("DoRetry() = %v after %d calls, want *PanicError after 1", err, calls)
+	}
+
+	cfg.RepanicOnGiveUp = true
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("recover() = %v, want boom", r)
+		}
+	}()
+	calls = 0
+	_ = DoRetry(context.Background(), cfg, panicking(10, &calls), []error{ErrPanic})
+	t.Errorf("DoRetry() returned, want a panic")
+}
+
+func TestMaxDurationExceededError(t *testing.T) {
+	errTest := errors.New("test")
+	clock := goretrytest.NewFakeClock(time.Now())
+	cfg := Config{InitialDelay: time.Second, Infinite: true, MaxDuration: 3500 * time.Millisecond, Clock: clock}
+
+	err := DoRetry(context.Background(), cfg, func(context.Context) error {
+		return errTest
+	}, []error{errTest})
+
+	var durationErr *MaxDurationExceededError
+	if !errors.As(err, &durationErr) {
+		t.Fatalf("DoRetry() = %v, want *MaxDurationExceededError", err)
+	}
+	if !errors.Is(err, errTest) {
+		t.Errorf("DoRetry() = %v, want it to wrap %v", err, errTest)
+	}
+	if durationErr.MaxDuration != cfg.MaxDuration || durationErr.Elapsed != cfg.MaxDuration || durationErr.Attempts != 5 {
+		t.Errorf("MaxDurationExceededError = %+v, want 5 attempts in %s", durationErr, cfg.MaxDuration)
+	}
+}
+
+type retryAfterError struct {
+	delay time.Duration
+}
+
+func (e retryAfterError) Error() string {
+	return "retry after " + e.delay.String()
+}
+
+func (e retryAfterError) RetryAfter() time.Duration {
+	return e.delay
+}
+
+func TestRetryAfterError(t *testing.T) {
+	for _, tt := range []struct {
+		suggested time.Duration
+		want      time.Duration
+	}{
+		{suggested: 5 * time.Second, want: 5 * time.Second},
+		{suggested: 500 * time.Millisecond, want: time.Second},
+	} {
+		clock := goretrytest.NewFakeClock(time.Now())
+		cfg := Config{InitialDelay: time.Second, MaxRetries: 1, Clock: clock}
+		errTest := retryAfterError{delay: tt.suggested}
+
+		calls := 0
+		_ = DoRetry(context.Background(), cfg, failFor(1, errTest, &calls), []error{errTest})
+		if got := clock.Sleeps(); len(got) != 1 || got[0] != tt.want {
+			t.Errorf("sleeps with a Retry-After of %s = %v, want [%s]", tt.suggested, got, tt.want)
+		}
+	}
+}
+
+func TestRetryAfterErrorLimits(t *testing.T) {
+	clock := goretrytest.NewFakeClock(time.Now())
+	cfg := Config{InitialDelay: time.Second, MaxRetries: 3, MaxDelay: 2 * time.Second, Clock: clock}
+	errTest := retryAfterError{delay: time.Hour}
+
+	calls := 0
+	_ = DoRetry(context.Background(), cfg, failFor(1, errTest, &calls), []error{errTest})
+	if got := clock.Sleeps(); !reflect.DeepEqual(got, []time.Duration{2 * time.Second}) {
+		t.Errorf("sleeps with MaxDelay %s = %v, want [%s]", cfg.MaxDelay, got, cfg.MaxDelay)
+	}
+
+	clock = goretrytest.NewFakeClock(time.Now())
+	cfg = Config{InitialDelay: time.Second, MaxRetries: 3, MaxDuration: 10 * time.Second, Clock: clock}
+	calls = 0
+	err := DoRetry(context.Background(), cfg, failFor(10, errTest, &calls), []error{errTest})
+	var maxDurationErr *MaxDurationExceededError
+	if !errors.As(err, &maxDurationErr) || !errors.Is(err, errTest) || calls != 1 || len(clock.Sleeps()) != 0 {
+		t.Errorf("DoRetry() = %v after %d calls and sleeps %v, want *MaxDurationExceededError after 1 call without sleeping", err, calls, clock.Sleeps())
+	}
+}
+
+func TestResetBackoff(t *testing.T) {
+	errTest := errors.New("test")
+	clock := goretrytest.NewFakeClock(time.Now())
+	cfg := Config{InitialDelay: time.Second, BackoffType: Exponential, MaxRetries: 3, Clock: clock}
+
+	calls := 0
+	err := DoRetry(context.Background(), cfg, func(context.Context) error {
+		calls++
+		switch {
+		case calls == 3:
+			return ResetBackoff(errTest)
+		case calls < 6:
+			return errTest
+		default:
+			return nil
+		}
+	}, []error{errTest})
+	if err != nil || calls != 6 {
+		t.Errorf("DoRetry() = %v after %d calls, want nil after 6", err, calls)
+	}
+
+	s := time.Second
+	if want := []time.Duration{s, 2 * s, s, 2 * s, 4 * s}; !reflect.DeepEqual(clock.Sleeps(), want) {
+		t.Errorf("sleeps = %v, want %v", clock.Sleeps(), want)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	errTest := errors.New("test")
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "marked", err: RetryableError(errTest), want: true},
+		{name: "wrapped marked", err: fmt.Errorf("call: %w", RetryableError(errTest)), want: true},
+		{name: "unmarked", err: errTest},
+		{name: "wrapped unmarked", err: fmt.Errorf("call: %w", errTest)},
+		{name: "permanent", err: PermanentError(errTest)},
+		{name: "nil", err: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryableErrorNil(t *testing.T) {
+	errTest := errors.New("test")
+
+	if err := RetryableError(nil); err != nil {
+		t.Errorf("RetryableError(nil) = %v, want nil", err)
+	}
+
+	calls := 0
+	if err := DoRetry(context.Background(), fastConfig(2), failFor(1, errTest, &calls), []error{nil, errTest}); err != nil || calls != 2 {
+		t.Errorf("DoRetry() with a nil retryable error = %v after %d calls, want nil after 2", err, calls)
+	}
+
+	calls = 0
+	cfg := fastConfig(2)
+	cfg.MatchByMessage = true
+	if err := DoRetry(context.Background(), cfg, failFor(1, os.ErrExist, &calls), []error{nil}); err != os.ErrExist || calls != 1 {
+		t.Errorf("DoRetry() with only a nil retryable error = %v after %d calls, want %v after 1", err, calls, os.ErrExist)
+	}
+}
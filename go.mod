@@ -2,4 +2,4 @@ module github.com/harlesbayu/go-retry
 
 go 1.21.4
 
-require github.com/sethvargo/go-retry v0.3.0 // indirect
+require github.com/sethvargo/go-retry v0.3.0
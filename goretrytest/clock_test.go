@@ -0,0 +1,53 @@
+package goretrytest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	goretry "github.com/harlesbayu/go-retry"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	if err := clock.Sleep(context.Background(), time.Second); err != nil {
+		t.Fatalf("Sleep() = %v, want nil", err)
+	}
+	clock.Advance(time.Minute)
+	if got := clock.Now().Sub(start); got != time.Minute+time.Second {
+		t.Errorf("Now() = start + %s, want start + 1m1s", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := clock.Sleep(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("Sleep() with a done context = %v, want %v", err, context.Canceled)
+	}
+	if got := clock.Sleeps(); !reflect.DeepEqual(got, []time.Duration{time.Second}) {
+		t.Errorf("Sleeps() = %v, want [1s]", got)
+	}
+}
+
+func TestFakeClockRetry(t *testing.T) {
+	errTest := errors.New("test")
+	clock := NewFakeClock(time.Now())
+	cfg := goretry.Config{InitialDelay: time.Minute, MaxRetries: 10, Clock: clock}
+
+	start := time.Now()
+	count, err := goretry.DoRetryCount(context.Background(), cfg, func(context.Context) error {
+		return errTest
+	}, []error{errTest})
+	if !errors.Is(err, errTest) || count != 11 {
+		t.Errorf("DoRetryCount() = %d, %v, want 11 attempts", count, err)
+	}
+	if got := len(clock.Sleeps()); got != 10 {
+		t.Errorf("sleeps = %d, want 10", got)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("DoRetryCount() took %s, want it to return instantly", elapsed)
+	}
+}
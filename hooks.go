@@ -0,0 +1,24 @@
+package goretry
+
+import (
+	"context"
+	"time"
+)
+
+// TraceHook is used to trace every attempt, e.g. to start a span per attempt
+type TraceHook interface {
+	// StartAttempt is called before fn with the attempt number starting from 1, the returned context is passed to fn and the returned func is called with the error of fn
+	StartAttempt(ctx context.Context, attempt int) (context.Context, func(error))
+}
+
+// MetricsHook is used to observe the attempts, delays and outcome of a retry, e.g. to feed counters and histograms
+type MetricsHook interface {
+	// ObserveAttempt is called after every attempt with the error of fn
+	ObserveAttempt(err error)
+
+	// ObserveDelay is called with the delay before every retry
+	ObserveDelay(d time.Duration)
+
+	// ObserveOutcome is called once when the retry finishes
+	ObserveOutcome(success bool, attempts int)
+}
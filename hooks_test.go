@@ -0,0 +1,99 @@
+package goretry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type spanKey struct{}
+
+type fakeTraceHook struct {
+	events []string
+}
+
+func (h *fakeTraceHook) StartAttempt(ctx context.Context, attempt int) (context.Context, func(error)) {
+	h.events = append(h.events, "start")
+
+	return context.WithValue(ctx, spanKey{}, attempt), func(err error) {
+		if err != nil {
+			h.events = append(h.events, "end: "+err.Error())
+			return
+		}
+		h.events = append(h.events, "end")
+	}
+}
+
+func TestTraceHook(t *testing.T) {
+	errTest := errors.New("test")
+
+	hook := &fakeTraceHook{}
+	cfg := fastConfig(3)
+	cfg.TraceHook = hook
+
+	var spans []any
+	calls := 0
+	_ = DoRetry(context.Background(), cfg, func(ctx context.Context) error {
+		spans = append(spans, ctx.Value(spanKey{}))
+		return failFor(2, errTest, &calls)(ctx)
+	}, []error{errTest})
+
+	if want := []string{"start", "end: test", "start", "end: test", "start", "end"}; !reflect.DeepEqual(hook.events, want) {
+		t.Errorf("events = %q, want %q", hook.events, want)
+	}
+	if want := []any{1, 2, 3}; !reflect.DeepEqual(spans, want) {
+		t.Errorf("fn contexts = %v, want the contexts returned by StartAttempt %v", spans, want)
+	}
+}
+
+type fakeMetricsHook struct {
+	attempts []error
+	delays   []time.Duration
+	outcomes []string
+}
+
+func (h *fakeMetricsHook) ObserveAttempt(err error) {
+	h.attempts = append(h.attempts, err)
+}
+
+func (h *fakeMetricsHook) ObserveDelay(d time.Duration) {
+	h.delays = append(h.delays, d)
+}
+
+func (h *fakeMetricsHook) ObserveOutcome(success bool, attempts int) {
+	h.outcomes = append(h.outcomes, fmt.Sprintf("%t %d", success, attempts))
+}
+
+func TestMetricsHook(t *testing.T) {
+	errTest := errors.New("test")
+
+	for _, tt := range []struct {
+		retries  int
+		failures int
+		want     fakeMetricsHook
+	}{
+		{retries: 3, failures: 2, want: fakeMetricsHook{
+			attempts: []error{errTest, errTest, nil},
+			delays:   []time.Duration{time.Millisecond, time.Millisecond},
+			outcomes: []string{"true 3"},
+		}},
+		{retries: 1, failures: 10, want: fakeMetricsHook{
+			attempts: []error{errTest, errTest},
+			delays:   []time.Duration{time.Millisecond},
+			outcomes: []string{"false 2"},
+		}},
+	} {
+		hook := &fakeMetricsHook{}
+		cfg := fastConfig(tt.retries)
+		cfg.MetricsHook = hook
+
+		calls := 0
+		_ = DoRetry(context.Background(), cfg, failFor(tt.failures, errTest, &calls), []error{errTest})
+		if !reflect.DeepEqual(*hook, tt.want) {
+			t.Errorf("observations = %+v, want %+v", *hook, tt.want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package goretry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTPStatusError is an error carrying the status code and the Retry-After delay of an HTTP response
+type HTTPStatusError struct {
+	StatusCode      int
+	RetryAfterDelay time.Duration
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("goretry: http status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// RetryAfter implements RetryAfterError
+func (e *HTTPStatusError) RetryAfter() time.Duration {
+	return e.RetryAfterDelay
+}
+
+// ParseRetryAfter parses a Retry-After header value in seconds or as an HTTP date, it returns 0 for an empty or invalid value
+func ParseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
+
+// RetryableStatus returns a predicate for DoRetryIf that reports true for a *HTTPStatusError with one of the statuses
+func RetryableStatus(statuses ...int) func(error) bool {
+	return func(err error) bool {
+		var statusErr *HTTPStatusError
+		if !errors.As(err, &statusErr) {
+			return false
+		}
+
+		for _, v := range statuses {
+			if statusErr.StatusCode == v {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// maxDrain is the number of bytes read from a discarded response body so the connection can be reused
+const maxDrain = 64 << 10
+
+/*
+DoRetryHTTP will perform a retry when fn returns an error or a response with one of the retryStatuses
+
+Notes:
+  - The Retry-After header of a retried response is used as the minimum next delay
+  - The body of every discarded response is drained and closed
+  - When the retries are used up on a retryable status, the last response is returned with a nil error and its body must be closed by the caller
+  - Errors marked with PermanentError stop the retry immediately
+  - AttemptTimeout limits the request until its body is closed, the body of the returned response can be read after the retry ends
+*/
+func DoRetryHTTP(ctx context.Context, cfg Config, fn func(context.Context) (*http.Response, error), retryStatuses []int) (*http.Response, error) {
+	cfg = resolveConfig(cfg)
+	if err := cfg.Validate(); err != nil && !cfg.Disabled {
+		return nil, err
+	}
+	// the attempt timeout is applied here, the context of an attempt is cancelled when the attempt returns
+	attemptTimeout := cfg.AttemptTimeout
+	cfg.AttemptTimeout = 0
+
+	var last *http.Response
+	resp, _, err := doRetry(ctx, cfg, func(ctx context.Context) (*http.Response, error) {
+		if last != nil {
+			drainBody(last)
+			last = nil
+		}
+
+		cancel := context.CancelFunc(func() {})
+		if attemptTimeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, attemptTimeout)
+		}
+
+		resp, err := fn(ctx)
+		if err != nil {
+			if resp != nil {
+				drainBody(resp)
+			}
+			cancel()
+			return nil, err
+		}
+		if resp.Body == nil {
+			cancel()
+		} else {
+			resp.Body = cancelBody{ReadCloser: resp.Body, cancel: cancel}
+		}
+
+		for _, v := range retryStatuses {
+			if resp.StatusCode == v {
+				last = resp
+				return nil, &HTTPStatusError{StatusCode: resp.StatusCode, RetryAfterDelay: ParseRetryAfter(resp.Header.Get("Retry-After"))}
+			}
+		}
+
+		return resp, nil
+	}, func(error) bool {
+		return true
+	})
+
+	if last != nil {
+		var ctxErr *ContextError
+		if errors.As(err, &ctxErr) {
+			drainBody(last)
+			return nil, err
+		}
+		return last, nil
+	}
+
+	return resp, err
+}
+
+// cancelBody cancels the context of the request when the body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+
+	return err
+}
+
+// drainBody reads the rest of the body of resp and closes it
+func drainBody(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrain))
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,160 @@
+package goretry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryableStatus(t *testing.T) {
+	retryable := RetryableStatus(http.StatusTooManyRequests, http.StatusServiceUnavailable)
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "429", err: &HTTPStatusError{StatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "wrapped 503", err: fmt.Errorf("get: %w", &HTTPStatusError{StatusCode: http.StatusServiceUnavailable}), want: true},
+		{name: "500", err: &HTTPStatusError{StatusCode: http.StatusInternalServerError}},
+		{name: "200 wrapped as error", err: fmt.Errorf("get: %w", &HTTPStatusError{StatusCode: http.StatusOK})},
+		{name: "other error", err: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err); got != tt.want {
+				t.Errorf("RetryableStatus()(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+
+			calls := 0
+			_ = DoRetryIf(context.Background(), fastConfig(1), failFor(1, tt.err, &calls), retryable)
+			if retried := calls == 2; retried != tt.want {
+				t.Errorf("DoRetryIf() calls = %d, want retried %t", calls, tt.want)
+			}
+		})
+	}
+}
+
+type trackedBody struct {
+	io.ReadCloser
+	closed *int
+}
+
+func (b trackedBody) Close() error {
+	*b.closed++
+	return b.ReadCloser.Close()
+}
+
+func TestDoRetryHTTP(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = io.WriteString(w, "unavailable")
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	closed := 0
+	get := func(ctx context.Context) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = trackedBody{ReadCloser: resp.Body, closed: &closed}
+
+		return resp, nil
+	}
+
+	resp, err := DoRetryHTTP(context.Background(), fastConfig(3), get, []int{http.StatusServiceUnavailable})
+	if err != nil {
+		t.Fatalf("DoRetryHTTP() error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "ok" || requests != 3 {
+		t.Errorf("DoRetryHTTP() = %d %q after %d requests, want 200 ok after 3", resp.StatusCode, body, requests)
+	}
+	if closed != 3 {
+		t.Errorf("closed bodies = %d, want the 2 discarded bodies and the returned one", closed)
+	}
+
+	atomic.StoreInt32(&requests, 0)
+	closed = 0
+	resp, err = DoRetryHTTP(context.Background(), fastConfig(1), get, []int{http.StatusServiceUnavailable})
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("DoRetryHTTP() = %v, %v, want the last 503 response", resp, err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "unavailable" || closed != 2 {
+		t.Errorf("last response body = %q with %d closed bodies, want unavailable with 2", body, closed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: 0},
+		{value: "5", want: 5 * time.Second},
+		{value: "-1", want: 0},
+		{value: "soon", want: 0},
+		{value: time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), want: 0},
+	}
+	for _, tt := range tests {
+		if got := ParseRetryAfter(tt.value); got != tt.want {
+			t.Errorf("ParseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+
+	if got := ParseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)); got <= 0 || got > time.Minute {
+		t.Errorf("ParseRetryAfter() of a date = %s, want up to 1m", got)
+	}
+}
+
+func TestDoRetryHTTPAttemptTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		time.Sleep(20 * time.Millisecond)
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	var reqCtx context.Context
+	get := func(ctx context.Context) (*http.Response, error) {
+		reqCtx = ctx
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		return srv.Client().Do(req)
+	}
+
+	cfg := fastConfig(2)
+	cfg.AttemptTimeout = time.Second
+	resp, err := DoRetryHTTP(context.Background(), cfg, get, []int{http.StatusServiceUnavailable})
+	if err != nil {
+		t.Fatalf("DoRetryHTTP() error = %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || string(body) != "ok" {
+		t.Errorf("returned body = %q, %v, want ok", body, err)
+	}
+	resp.Body.Close()
+	if reqCtx.Err() == nil {
+		t.Errorf("request context is not cancelled after the body is closed")
+	}
+}
@@ -0,0 +1,161 @@
+package goretry
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// duration is a time.Duration encoded as a string like "3s"
+type duration time.Duration
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"3s\": %w", err)
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = duration(v)
+	return nil
+}
+
+// configJSON is the JSON representation of the values of Config, callbacks, hooks and errors are not encoded
+type configJSON struct {
+	InitialDelay        duration      `json:"initial_delay"`
+	MaxRetries          int           `json:"max_retries"`
+	MaxAttempts         int           `json:"max_attempts,omitempty"`
+	Infinite            bool          `json:"infinite,omitempty"`
+	BackoffType         BackoffType   `json:"backoff_type,omitempty"`
+	Jitter              duration      `json:"jitter"`
+	JitterMode          JitterMode    `json:"jitter_mode,omitempty"`
+	JitterPercent       float64       `json:"jitter_percent,omitempty"`
+	JitterOnlyFor       []BackoffType `json:"jitter_only_for,omitempty"`
+	MaxDuration         duration      `json:"max_duration"`
+	MaxDelay            duration      `json:"max_delay"`
+	Multiplier          float64       `json:"multiplier,omitempty"`
+	AbsoluteMaxCalls    int           `json:"absolute_max_calls,omitempty"`
+	EscalateOnRepeat    bool          `json:"escalate_on_repeat,omitempty"`
+	JitterMaxFraction   float64       `json:"jitter_max_fraction,omitempty"`
+	MinInterval         duration      `json:"min_interval"`
+	RandomizeFirstDelay bool          `json:"randomize_first_delay,omitempty"`
+	FirstAttemptDelay   duration      `json:"first_attempt_delay"`
+	AttemptTimeout      duration      `json:"attempt_timeout"`
+	UseContextDeadline  bool          `json:"use_context_deadline,omitempty"`
+	Disabled            bool          `json:"disabled,omitempty"`
+	JoinErrors          bool          `json:"join_errors,omitempty"`
+	MatchByMessage      bool          `json:"match_by_message,omitempty"`
+	RecoverPanics       bool          `json:"recover_panics,omitempty"`
+	RepanicOnGiveUp     bool          `json:"repanic_on_give_up,omitempty"`
+	Concurrency         int           `json:"concurrency,omitempty"`
+	ReturnSuccessError  bool          `json:"return_success_error,omitempty"`
+	RetryTemporary      bool          `json:"retry_temporary,omitempty"`
+	RetryOnlyListed     bool          `json:"retry_only_listed,omitempty"`
+	Schedule            []duration    `json:"schedule,omitempty"`
+	IdempotencyKey      string        `json:"idempotency_key,omitempty"`
+}
+
+// MarshalJSON encodes the values of the configuration with durations as strings, callbacks, hooks and errors are not encoded
+func (c Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newConfigJSON(c))
+}
+
+// UnmarshalJSON decodes the values encoded by MarshalJSON, missing values keep the existing value and the result is validated
+func (c *Config) UnmarshalJSON(b []byte) error {
+	v := newConfigJSON(*c)
+	if err := json.Unmarshal(b, &v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	cfg := *c
+	cfg.InitialDelay = time.Duration(v.InitialDelay)
+	cfg.MaxRetries = v.MaxRetries
+	cfg.MaxAttempts = v.MaxAttempts
+	cfg.Infinite = v.Infinite
+	cfg.BackoffType = v.BackoffType
+	cfg.Jitter = time.Duration(v.Jitter)
+	cfg.JitterMode = v.JitterMode
+	cfg.JitterPercent = v.JitterPercent
+	cfg.JitterOnlyFor = append([]BackoffType(nil), v.JitterOnlyFor...)
+	cfg.MaxDuration = time.Duration(v.MaxDuration)
+	cfg.MaxDelay = time.Duration(v.MaxDelay)
+	cfg.Multiplier = v.Multiplier
+	cfg.AbsoluteMaxCalls = v.AbsoluteMaxCalls
+	cfg.EscalateOnRepeat = v.EscalateOnRepeat
+	cfg.JitterMaxFraction = v.JitterMaxFraction
+	cfg.MinInterval = time.Duration(v.MinInterval)
+	cfg.RandomizeFirstDelay = v.RandomizeFirstDelay
+	cfg.FirstAttemptDelay = time.Duration(v.FirstAttemptDelay)
+	cfg.AttemptTimeout = time.Duration(v.AttemptTimeout)
+	cfg.UseContextDeadline = v.UseContextDeadline
+	cfg.Disabled = v.Disabled
+	cfg.JoinErrors = v.JoinErrors
+	cfg.MatchByMessage = v.MatchByMessage
+	cfg.RecoverPanics = v.RecoverPanics
+	cfg.RepanicOnGiveUp = v.RepanicOnGiveUp
+	cfg.Concurrency = v.Concurrency
+	cfg.ReturnSuccessError = v.ReturnSuccessError
+	cfg.RetryTemporary = v.RetryTemporary
+	cfg.RetryOnlyListed = v.RetryOnlyListed
+	cfg.Schedule = nil
+	for _, d := range v.Schedule {
+		cfg.Schedule = append(cfg.Schedule, time.Duration(d))
+	}
+	cfg.IdempotencyKey = v.IdempotencyKey
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	*c = cfg
+	return nil
+}
+
+// newConfigJSON returns the JSON representation of c
+func newConfigJSON(c Config) configJSON {
+	v := configJSON{
+		InitialDelay:        duration(c.InitialDelay),
+		MaxRetries:          c.MaxRetries,
+		MaxAttempts:         c.MaxAttempts,
+		Infinite:            c.Infinite,
+		BackoffType:         c.BackoffType,
+		Jitter:              duration(c.Jitter),
+		JitterMode:          c.JitterMode,
+		JitterPercent:       c.JitterPercent,
+		JitterOnlyFor:       append([]BackoffType(nil), c.JitterOnlyFor...),
+		MaxDuration:         duration(c.MaxDuration),
+		MaxDelay:            duration(c.MaxDelay),
+		Multiplier:          c.Multiplier,
+		AbsoluteMaxCalls:    c.AbsoluteMaxCalls,
+		EscalateOnRepeat:    c.EscalateOnRepeat,
+		JitterMaxFraction:   c.JitterMaxFraction,
+		MinInterval:         duration(c.MinInterval),
+		RandomizeFirstDelay: c.RandomizeFirstDelay,
+		FirstAttemptDelay:   duration(c.FirstAttemptDelay),
+		AttemptTimeout:      duration(c.AttemptTimeout),
+		UseContextDeadline:  c.UseContextDeadline,
+		Disabled:            c.Disabled,
+		JoinErrors:          c.JoinErrors,
+		MatchByMessage:      c.MatchByMessage,
+		RecoverPanics:       c.RecoverPanics,
+		RepanicOnGiveUp:     c.RepanicOnGiveUp,
+		Concurrency:         c.Concurrency,
+		ReturnSuccessError:  c.ReturnSuccessError,
+		RetryTemporary:      c.RetryTemporary,
+		RetryOnlyListed:     c.RetryOnlyListed,
+		IdempotencyKey:      c.IdempotencyKey,
+	}
+	for _, d := range c.Schedule {
+		v.Schedule = append(v.Schedule, duration(d))
+	}
+
+	return v
+}
@@ -0,0 +1,54 @@
+package goretry
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigJSON(t *testing.T) {
+	cfg := DefaultConfigFor(Exponential)
+	cfg.MaxDelay = time.Minute
+	cfg.JitterMode = JitterFull
+	cfg.Schedule = []time.Duration{time.Second, 3 * time.Second}
+	cfg.JitterOnlyFor = []BackoffType{Exponential}
+
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	for _, want := range []string{`"initial_delay":"500ms"`, `"max_delay":"1m0s"`, `"backoff_type":"exponential"`, `"schedule":["1s","3s"]`} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("Marshal() = %s, want it to contain %s", b, want)
+		}
+	}
+
+	var got Config
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, cfg) {
+		t.Errorf("Unmarshal() = %+v, want %+v", got, cfg)
+	}
+}
+
+func TestConfigJSONInvalid(t *testing.T) {
+	for _, s := range []string{
+		`{"initial_delay":"1s","backoff_type":"quadratic"}`,
+		`{"initial_delay":1000}`,
+		`{"initial_delay":"soon"}`,
+	} {
+		var cfg Config
+		if err := json.Unmarshal([]byte(s), &cfg); err == nil {
+			t.Errorf("Unmarshal(%s) = nil, want an error", s)
+		}
+	}
+
+	var cfg Config
+	if err := json.Unmarshal([]byte(`{"initial_delay":"1s","backoff_type":"quadratic"}`), &cfg); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Unmarshal() = %v, want %v", err, ErrInvalidConfig)
+	}
+}
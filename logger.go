@@ -0,0 +1,22 @@
+package goretry
+
+// Logger is used to log the retry activity
+type Logger interface {
+	Debugf(format string, args ...any)
+	Warnf(format string, args ...any)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...any) {}
+
+func (nopLogger) Warnf(string, ...any) {}
+
+// getLogger returns the configured logger or a no-op logger when it is not set
+func getLogger(cfg Config) Logger {
+	if cfg.Logger == nil {
+		return nopLogger{}
+	}
+
+	return cfg.Logger
+}
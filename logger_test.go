@@ -0,0 +1,56 @@
+package goretry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type fakeLogger struct {
+	debug []string
+	warn  []string
+}
+
+func (l *fakeLogger) Debugf(format string, args ...any) {
+	l.debug = append(l.debug, fmt.Sprintf(format, args...))
+}
+
+func (l *fakeLogger) Warnf(format string, args ...any) {
+	l.warn = append(l.warn, fmt.Sprintf(format, args...))
+}
+
+func TestLogger(t *testing.T) {
+	errTest := errors.New("test")
+
+	logger := &fakeLogger{}
+	cfg := fastConfig(3)
+	cfg.Logger = logger
+	calls := 0
+	_ = DoRetry(context.Background(), cfg, failFor(2, errTest, &calls), []error{errTest})
+
+	if len(logger.debug) != 3 || len(logger.warn) != 0 {
+		t.Fatalf("logs = %q %q, want 2 retries and the outcome", logger.debug, logger.warn)
+	}
+	if !strings.Contains(logger.debug[0], "attempt 1") || !strings.Contains(logger.debug[0], "1ms") {
+		t.Errorf("retry log = %q, want the attempt and the delay", logger.debug[0])
+	}
+	if !strings.Contains(logger.debug[2], "succeeded after 3 attempts") {
+		t.Errorf("outcome log = %q, want the success", logger.debug[2])
+	}
+
+	logger = &fakeLogger{}
+	cfg.Logger = logger
+	calls = 0
+	_ = DoRetry(context.Background(), cfg, failFor(10, errTest, &calls), []error{errTest})
+	if len(logger.warn) != 1 || !strings.Contains(logger.warn[0], "failed after 4 attempts") {
+		t.Errorf("warn logs = %q, want the failure", logger.warn)
+	}
+
+	cfg.Logger = nil
+	calls = 0
+	if err := DoRetry(context.Background(), cfg, failFor(1, errTest, &calls), []error{errTest}); err != nil {
+		t.Errorf("DoRetry() without logger = %v, want nil", err)
+	}
+}
@@ -0,0 +1,66 @@
+package goretry
+
+import (
+	"context"
+	"time"
+)
+
+type options struct {
+	cfg            Config
+	retryableError []error
+}
+
+// Option changes the configuration used by Do
+type Option func(*options)
+
+// WithBackoffType sets the BackoffType
+func WithBackoffType(t BackoffType) Option {
+	return func(o *options) {
+		o.cfg.BackoffType = t
+	}
+}
+
+// WithInitialDelay sets the InitialDelay
+func WithInitialDelay(d time.Duration) Option {
+	return func(o *options) {
+		o.cfg.InitialDelay = d
+	}
+}
+
+// WithMaxRetries sets the MaxRetries
+func WithMaxRetries(n int) Option {
+	return func(o *options) {
+		o.cfg.MaxRetries = n
+	}
+}
+
+// WithMaxDuration sets the MaxDuration
+func WithMaxDuration(d time.Duration) Option {
+	return func(o *options) {
+		o.cfg.MaxDuration = d
+	}
+}
+
+// WithJitter sets the Jitter
+func WithJitter(d time.Duration) Option {
+	return func(o *options) {
+		o.cfg.Jitter = d
+	}
+}
+
+// WithRetryable adds errors that need to be retried
+func WithRetryable(errs ...error) Option {
+	return func(o *options) {
+		o.retryableError = append(o.retryableError, errs...)
+	}
+}
+
+// Do will perform a retry like DoRetry with DefaultConfig changed by opts, later options override earlier ones
+func Do(ctx context.Context, fn func(context.Context) error, opts ...Option) error {
+	o := options{cfg: DefaultConfig()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return DoRetry(ctx, o.cfg, fn, o.retryableError)
+}
@@ -0,0 +1,42 @@
+package goretry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	errA := errors.New("a")
+	errB := errors.New("b")
+	fast := []Option{WithInitialDelay(time.Millisecond), WithJitter(0)}
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		err       error
+		wantCalls int
+	}{
+		{name: "default retries", opts: []Option{WithRetryable(errA)}, err: errA, wantCalls: 4},
+		{name: "later option overrides", opts: []Option{WithMaxRetries(5), WithRetryable(errA), WithMaxRetries(1)}, err: errA, wantCalls: 2},
+		{name: "retryable errors compose", opts: []Option{WithRetryable(errA), WithRetryable(errB), WithMaxRetries(1)}, err: errB, wantCalls: 2},
+		{name: "not retryable", opts: []Option{WithRetryable(errA)}, err: errB, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := Do(context.Background(), failFor(10, tt.err, &calls), append(fast, tt.opts...)...)
+			if !errors.Is(err, tt.err) || calls != tt.wantCalls {
+				t.Errorf("Do() = %v after %d calls, want %v after %d", err, calls, tt.err, tt.wantCalls)
+			}
+		})
+	}
+
+	err := Do(context.Background(), func(context.Context) error {
+		return nil
+	}, WithBackoffType("quadratic"))
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Do() with an unknown backoff = %v, want %v", err, ErrInvalidConfig)
+	}
+}
@@ -0,0 +1,45 @@
+package goretry
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PlannedAttempt describes a single attempt of a RetryPlan
+type PlannedAttempt struct {
+	Attempt int
+	Delay   time.Duration
+	Elapsed time.Duration
+}
+
+// RetryPlan describes the attempts a configuration would perform when fn keeps failing
+type RetryPlan struct {
+	Attempts []PlannedAttempt
+}
+
+// Plan returns the plan of up to maxAttempts attempts, the delays are computed like PreviewDelays
+func (c Config) Plan(maxAttempts int) RetryPlan {
+	if maxAttempts <= 0 || c.Validate() != nil {
+		return RetryPlan{}
+	}
+
+	elapsed := c.FirstAttemptDelay
+	attempts := []PlannedAttempt{{Attempt: 1, Delay: c.FirstAttemptDelay, Elapsed: elapsed}}
+	for i, d := range c.PreviewDelays(maxAttempts - 1) {
+		elapsed += d
+		attempts = append(attempts, PlannedAttempt{Attempt: i + 2, Delay: d, Elapsed: elapsed})
+	}
+
+	return RetryPlan{Attempts: attempts}
+}
+
+// String returns a line per attempt with the delay before the attempt and the total elapsed delay
+func (p RetryPlan) String() string {
+	var sb strings.Builder
+	for _, v := range p.Attempts {
+		fmt.Fprintf(&sb, "attempt %d: after %s (total %s)\n", v.Attempt, v.Delay, v.Elapsed)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,43 @@
+package goretry
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfigPlan(t *testing.T) {
+	s := time.Second
+	tests := []struct {
+		name        string
+		backoffType BackoffType
+		want        []PlannedAttempt
+	}{
+		{name: "exponential", backoffType: Exponential, want: []PlannedAttempt{
+			{Attempt: 1}, {Attempt: 2, Delay: s, Elapsed: s}, {Attempt: 3, Delay: 2 * s, Elapsed: 3 * s},
+			{Attempt: 4, Delay: 4 * s, Elapsed: 7 * s}, {Attempt: 5, Delay: 8 * s, Elapsed: 15 * s},
+		}},
+		{name: "fibonacci", backoffType: Fibonacci, want: []PlannedAttempt{
+			{Attempt: 1}, {Attempt: 2, Delay: s, Elapsed: s}, {Attempt: 3, Delay: 2 * s, Elapsed: 3 * s},
+			{Attempt: 4, Delay: 3 * s, Elapsed: 6 * s}, {Attempt: 5, Delay: 5 * s, Elapsed: 11 * s},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{InitialDelay: s, BackoffType: tt.backoffType, MaxRetries: 10, Jitter: s}
+			if got := cfg.Plan(5); !reflect.DeepEqual(got.Attempts, tt.want) {
+				t.Errorf("Plan() = %+v, want %+v", got.Attempts, tt.want)
+			}
+		})
+	}
+
+	plan := Config{InitialDelay: s, MaxRetries: 1, FirstAttemptDelay: s}.Plan(5)
+	want := "attempt 1: after 1s (total 1s)\nattempt 2: after 1s (total 2s)\n"
+	if got := plan.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	if got := (Config{}).Plan(3); len(got.Attempts) != 0 {
+		t.Errorf("Plan() of an invalid config = %+v, want no attempts", got)
+	}
+}
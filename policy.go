@@ -0,0 +1,77 @@
+package goretry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pkgRetry "github.com/sethvargo/go-retry"
+)
+
+// ErrorPolicy pairs a matcher with the configuration used to retry the matched errors, a nil Match matches any error
+type ErrorPolicy struct {
+	Match  func(error) bool
+	Config Config
+}
+
+/*
+DoRetryWithPolicy will perform a retry where the next delay is governed by the policy matching the error
+
+Notes:
+  - Policies are checked in order and the first matching policy wins
+  - Each policy keeps its own backoff, the retry stops when the backoff of the matched policy stops. ResetBackoff restarts the backoffs of every policy
+  - Errors that match no policy are not retried, an error marked with RetryableError or ResetBackoff that matches no policy stops the retry with *RetriesExhaustedError
+  - Only the backoff settings of the policies are used (BackoffType, InitialDelay, Jitter, MaxDelay, MaxDuration and MaxRetries)
+*/
+func DoRetryWithPolicy(ctx context.Context, fn func(context.Context) error, policies []ErrorPolicy) error {
+	for i, p := range policies {
+		if err := p.Config.Validate(); err != nil {
+			return fmt.Errorf("policy %d: %w", i, err)
+		}
+	}
+
+	match := func(err error) int {
+		for i, p := range policies {
+			if p.Match == nil || p.Match(err) {
+				return i
+			}
+		}
+
+		return -1
+	}
+
+	var lastErr error
+	call := func(ctx context.Context) error {
+		lastErr = fn(ctx)
+		return lastErr
+	}
+
+	newBackoff := func(Config) pkgRetry.Backoff {
+		backoffs := make([]pkgRetry.Backoff, len(policies))
+		for i, p := range policies {
+			backoffs[i] = getBackoff(p.Config)
+		}
+
+		return pkgRetry.BackoffFunc(func() (time.Duration, bool) {
+			// the policy is matched again for every attempt from the error without its markers
+			i := match(unmarkError(lastErr))
+			if i < 0 {
+				return 0, true
+			}
+
+			return backoffs[i].Next()
+		})
+	}
+
+	// the backoffs of the policies decide when the retry stops, the outer configuration only drives the loop
+	cfg := Config{
+		InitialDelay: initialDelay,
+		Infinite:     true,
+	}
+
+	_, _, err := doRetryBackoff(ctx, cfg, newBackoff, noResult(call), func(err error) bool {
+		return match(err) >= 0
+	})
+
+	return err
+}
@@ -0,0 +1,134 @@
+package goretry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDoRetryWithPolicy(t *testing.T) {
+	errFast := errors.New("fast")
+	errSlow := errors.New("slow")
+	errOther := errors.New("other")
+
+	policies := func(fastRetries int) []ErrorPolicy {
+		return []ErrorPolicy{
+			{Match: MatchError(errFast), Config: Config{InitialDelay: time.Millisecond, MaxRetries: fastRetries}},
+			{Match: MatchError(errSlow), Config: Config{InitialDelay: 5 * time.Millisecond, MaxRetries: 1}},
+		}
+	}
+
+	t.Run("switches policy per error", func(t *testing.T) {
+		seq := []error{errFast, errSlow, errFast, nil}
+		calls := 0
+		err := DoRetryWithPolicy(context.Background(), func(context.Context) error {
+			calls++
+			return seq[calls-1]
+		}, policies(5))
+		if err != nil || calls != 4 {
+			t.Errorf("DoRetryWithPolicy() = %v after %d calls, want nil after 4", err, calls)
+		}
+	})
+
+	t.Run("stops with the matched policy", func(t *testing.T) {
+		calls := 0
+		err := DoRetryWithPolicy(context.Background(), func(context.Context) error {
+			calls++
+			return errSlow
+		}, policies(5))
+		var exhausted *RetriesExhaustedError
+		if !errors.As(err, &exhausted) || calls != 2 {
+			t.Errorf("DoRetryWithPolicy() = %v after %d calls, want *RetriesExhaustedError after 2", err, calls)
+		}
+	})
+
+	t.Run("unmatched error is not retried", func(t *testing.T) {
+		calls := 0
+		err := DoRetryWithPolicy(context.Background(), func(context.Context) error {
+			calls++
+			return errOther
+		}, policies(5))
+		if err != errOther || calls != 1 {
+			t.Errorf("DoRetryWithPolicy() = %v after %d calls, want %v after 1", err, calls, errOther)
+		}
+	})
+
+	t.Run("marked error matching no policy stops", func(t *testing.T) {
+		calls := 0
+		err := DoRetryWithPolicy(context.Background(), func(context.Context) error {
+			calls++
+			if calls == 1 {
+				return errFast
+			}
+			return RetryableError(errOther)
+		}, policies(5))
+		var exhausted *RetriesExhaustedError
+		if !errors.As(err, &exhausted) || !errors.Is(err, errOther) || calls != 2 {
+			t.Errorf("DoRetryWithPolicy() = %v after %d calls, want *RetriesExhaustedError wrapping %v after 2", err, calls, errOther)
+		}
+	})
+
+	t.Run("reset marker is matched", func(t *testing.T) {
+		calls := 0
+		err := DoRetryWithPolicy(context.Background(), func(context.Context) error {
+			calls++
+			if calls < 4 {
+				return ResetBackoff(errFast)
+			}
+			return nil
+		}, policies(1))
+		if err != nil || calls != 4 {
+			t.Errorf("DoRetryWithPolicy() = %v after %d calls, want nil after 4", err, calls)
+		}
+	})
+
+	t.Run("ignores the default configuration", func(t *testing.T) {
+		setDefaultConfig(t, Config{InitialDelay: time.Millisecond, MaxDuration: time.Millisecond, MaxRetries: 1})
+
+		calls := 0
+		err := DoRetryWithPolicy(context.Background(), func(context.Context) error {
+			calls++
+			return errSlow
+		}, []ErrorPolicy{{Config: Config{InitialDelay: 2 * time.Millisecond, MaxRetries: 3}}})
+		var exhausted *RetriesExhaustedError
+		if !errors.As(err, &exhausted) || calls != 4 {
+			t.Errorf("DoRetryWithPolicy() = %v after %d calls, want *RetriesExhaustedError after 4", err, calls)
+		}
+	})
+
+	t.Run("delays follow the matched policy", func(t *testing.T) {
+		elapsed := func(err error) time.Duration {
+			calls := 0
+			start := time.Now()
+			_ = DoRetryWithPolicy(context.Background(), func(context.Context) error {
+				calls++
+				if calls == 1 {
+					return err
+				}
+				return nil
+			}, []ErrorPolicy{
+				{Match: MatchError(errFast), Config: Config{InitialDelay: time.Millisecond}},
+				{Match: MatchError(errSlow), Config: Config{InitialDelay: 50 * time.Millisecond}},
+			})
+
+			return time.Since(start)
+		}
+
+		if got := elapsed(errSlow); got < 50*time.Millisecond {
+			t.Errorf("slow policy waited %s, want at least 50ms", got)
+		}
+		if got := elapsed(errFast); got >= 50*time.Millisecond {
+			t.Errorf("fast policy waited %s, want less than 50ms", got)
+		}
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		err := DoRetryWithPolicy(context.Background(), func(context.Context) error {
+			return nil
+		}, []ErrorPolicy{{Config: Config{InitialDelay: -1}}})
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("DoRetryWithPolicy() = %v, want %v", err, ErrInvalidConfig)
+		}
+	})
+}
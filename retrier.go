@@ -0,0 +1,32 @@
+package goretry
+
+import "context"
+
+// Retrier holds a configuration and a list of retryable errors to be reused across retries
+type Retrier struct {
+	cfg            Config
+	retryableError []error
+}
+
+// NewRetrier creates a Retrier with the configuration and the list of errors that need to be retried
+func NewRetrier(cfg Config, retryableErrors ...error) *Retrier {
+	return &Retrier{
+		cfg:            cfg,
+		retryableError: append([]error(nil), retryableErrors...),
+	}
+}
+
+// Do will perform a retry like DoRetry using the configuration and retryable errors of the Retrier
+func (r *Retrier) Do(ctx context.Context, fn func(context.Context) error) error {
+	return DoRetry(ctx, r.cfg, fn, r.retryableError)
+}
+
+// DoResult will perform a retry like DoRetryWithResult using the configuration and retryable errors of r, Go does not allow generic methods
+func DoResult[T any](ctx context.Context, r *Retrier, fn func(context.Context) (T, error)) (T, error) {
+	return DoRetryWithResult(ctx, r.cfg, fn, r.retryableError)
+}
+
+// DoResultWithFallback will perform a retry like DoRetryWithFallback using the configuration and retryable errors of r
+func DoResultWithFallback[T any](ctx context.Context, r *Retrier, fn func(context.Context) (T, error), fallback func(context.Context) (T, error)) (T, error) {
+	return DoRetryWithFallback(ctx, r.cfg, fn, fallback, r.retryableError)
+}
@@ -0,0 +1,51 @@
+package goretry
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRetrier(t *testing.T) {
+	errTest := errors.New("test")
+	r := NewRetrier(fastConfig(2), errTest)
+
+	for i := 0; i < 3; i++ {
+		calls := 0
+		if err := r.Do(context.Background(), failFor(2, errTest, &calls)); err != nil || calls != 3 {
+			t.Errorf("Do() call %d = %v after %d calls, want nil after 3", i, err, calls)
+		}
+	}
+
+	calls := 0
+	if err := r.Do(context.Background(), failFor(1, errors.New("other"), &calls)); err == nil || calls != 1 {
+		t.Errorf("Do() = %v after %d calls, want the error after 1", err, calls)
+	}
+
+	calls = 0
+	got, err := DoResult(context.Background(), r, func(ctx context.Context) (string, error) {
+		if err := failFor(1, errTest, &calls)(ctx); err != nil {
+			return "", err
+		}
+		return "ok", nil
+	})
+	if err != nil || got != "ok" || calls != 2 {
+		t.Errorf("DoResult() = %q, %v after %d calls, want ok after 2", got, err, calls)
+	}
+}
+
+func TestDoResultWithFallback(t *testing.T) {
+	errTest := errors.New("test")
+	r := NewRetrier(fastConfig(2), errTest)
+
+	calls := 0
+	got, err := DoResultWithFallback(context.Background(), r, func(context.Context) (string, error) {
+		calls++
+		return "", errTest
+	}, func(context.Context) (string, error) {
+		return "fallback", nil
+	})
+	if err != nil || got != "fallback" || calls != 3 {
+		t.Errorf("DoResultWithFallback() = %q, %v after %d calls, want fallback after 3", got, err, calls)
+	}
+}
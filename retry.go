@@ -2,6 +2,14 @@ package goretry
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"reflect"
+	"runtime/debug"
+	"sync"
 	"time"
 
 	pkgRetry "github.com/sethvargo/go-retry"
@@ -9,37 +17,178 @@ import (
 
 type BackoffType string
 
+type JitterMode string
+
 const (
-	maxRetries   int         = 3
-	initialDelay             = 3 * time.Second
-	maxDuration              = 10 * time.Second
-	jitter                   = 200 * time.Millisecond
-	Fibonacci    BackoffType = "fibonacci"
-	Constant     BackoffType = "constant"
-	Exponential  BackoffType = "exponential"
+	maxRetries         int         = 3
+	initialDelay                   = 3 * time.Second
+	maxDuration                    = 10 * time.Second
+	jitter                         = 200 * time.Millisecond
+	multiplier                     = 2.0
+	Fibonacci          BackoffType = "fibonacci"
+	Constant           BackoffType = "constant"
+	Exponential        BackoffType = "exponential"
+	DecorrelatedJitter BackoffType = "decorrelated_jitter"
+	Linear             BackoffType = "linear"
+	Random             BackoffType = "random"
+	JitterAdditive     JitterMode  = "additive"
+	JitterFull         JitterMode  = "full"
+	JitterEqual        JitterMode  = "equal"
+	JitterNone         JitterMode  = "none"
 )
 
 type Config struct {
-	InitialDelay time.Duration
-	MaxRetries   int
-	BackoffType  BackoffType
-	Jitter       time.Duration
-	MaxDuration  time.Duration
+	InitialDelay  time.Duration
+	MaxRetries    int
+	MaxAttempts   int
+	BackoffType   BackoffType
+	Jitter        time.Duration
+	JitterMode    JitterMode
+	JitterPercent float64
+	MaxDuration   time.Duration
+	MaxDelay      time.Duration
+	Multiplier    float64
+
+	// EscalateOnRepeat doubles the delay for every consecutive attempt failing with the same error, the innermost errors of the chains are compared with errors.Is. MaxDelay still caps it
+	EscalateOnRepeat bool
+
+	// AbsoluteMaxCalls caps the number of fn invocations regardless of the other values, e.g. to guard against infinite retries without delay
+	AbsoluteMaxCalls int
+
+	// JitterOnlyFor lists the backoff types the jitter is applied to, the jitter is applied to every type when it is empty
+	JitterOnlyFor []BackoffType
+
+	// JitterMaxFraction clamps the additive Jitter to this fraction of the delay, e.g. "0.5" keeps the jitter within half the delay
+	JitterMaxFraction float64
+
+	// MinInterval is the minimum delay between attempts, it applies after the jitter and CustomBackoff but the delay is still capped by MaxDelay
+	MinInterval time.Duration
+
+	// RandomizeFirstDelay replaces the delay before the first retry with a random delay between 0 and the delay, e.g. to spread instances started together
+	RandomizeFirstDelay bool
+
+	// Infinite retries without any limit on the number of retries, it overrides MaxRetries while MaxDuration and AbsoluteMaxCalls still apply
+	Infinite bool
+
+	// FirstAttemptDelay is waited before the first attempt, it is not counted in MaxDuration
+	FirstAttemptDelay time.Duration
+
+	// AttemptTimeout limits the duration of a single attempt, an attempt that exceeds it is retried
+	AttemptTimeout time.Duration
+
+	// UseContextDeadline stops the retry when the next delay would cross the deadline of the context
+	UseContextDeadline bool
+
+	// Disabled invokes fn exactly once without any retry, regardless of the other values. The PermanentError, ResetBackoff and RetryableError markers are removed from the error
+	Disabled bool
+
+	// JoinErrors returns the errors of all attempts joined with errors.Join instead of only the last error
+	JoinErrors bool
+
+	// MatchByMessage falls back to comparing error messages when matching retryable errors
+	MatchByMessage bool
+
+	// RecoverPanics converts a panic in fn into a *PanicError, add ErrPanic to the retryable errors to retry it
+	RecoverPanics bool
+
+	// RepanicOnGiveUp panics again with the recovered value instead of returning the *PanicError of the last attempt
+	RepanicOnGiveUp bool
+
+	// Concurrency limits the number of functions retried in parallel by DoRetryBatch, zero runs all of them in parallel
+	Concurrency int
+
+	// SuccessErrors are errors that mean fn is done, e.g. io.EOF. A matching error stops the retry and nil is returned
+	SuccessErrors []error
+
+	// ReturnSuccessError returns the matched error of SuccessErrors instead of nil
+	ReturnSuccessError bool
+
+	// RetryOnlyListed retries only the listed errors, marked errors, attempt timeouts and temporary errors are not retried unless listed.
+	// An empty list never retries plain errors whatever the flag, with the flag an empty list fails fast on every error
+	RetryOnlyListed bool
+
+	// RetryTemporary retries any error implementing "Temporary() bool" that returns true, like the legacy net errors
+	RetryTemporary bool
+
+	// Schedule lists the delays before every retry, when set BackoffType, InitialDelay and MaxRetries are ignored and the retry stops after the last delay
+	Schedule []time.Duration
+
+	// CustomBackoff returns the delay before the retry of the attempt starting from 1, when set BackoffType and InitialDelay are ignored
+	CustomBackoff func(attempt int) time.Duration
+
+	// AdaptiveFunc returns a duration added to the next delay from the latency of the failed attempt, e.g. to back off faster from a slow downstream.
+	// The adapted delay is still capped by MaxDelay and the time left in MaxDuration
+	AdaptiveFunc func(lastLatency time.Duration) time.Duration
+
+	// DelayFunc transforms the computed delay before the retry of the attempt starting from 1, the returned delay is the one waited
+	DelayFunc func(attempt int, computed time.Duration) time.Duration
+
+	// BeforeRetry is called before every retry of a retryable error once the delay is known, it is not called after the last attempt. Returning false stops the retry and the error is returned unwrapped
+	BeforeRetry func(ctx context.Context, attempt int, err error) bool
+
+	// OnRetry is called after every failed attempt that will be retried, attempt starts from 1 and nextDelay is the delay actually waited including the jitter
+	OnRetry func(attempt int, err error, nextDelay time.Duration)
+
+	// OnSuccess is called once when fn succeeds, with the number of attempts and the total elapsed time
+	OnSuccess func(attempts int, totalElapsed time.Duration)
+
+	// OnFlaky is called once when fn succeeds after at least one failure, with the errors of the failed attempts
+	OnFlaky func(attempts int, errs []error)
+
+	// OnGiveUp is called once when MaxRetries or MaxDuration stops the retry, it is not called on context cancellation or non retryable errors
+	OnGiveUp func(attempts int, lastErr error)
+
+	// RandSource is used to compute the jitter, it can be shared between concurrent retries but must not be used elsewhere at the same time. Defaults to a time seeded source
+	RandSource *rand.Rand
+
+	// IdempotencyKey seeds the jitter so the same key always produces the same delays, it is ignored when RandSource is set
+	IdempotencyKey string
+
+	// Logger logs every retry and the final outcome, nothing is logged when it is not set
+	Logger Logger
+
+	// TraceHook is called around every attempt
+	TraceHook TraceHook
+
+	// MetricsHook observes every attempt, delay and the final outcome
+	MetricsHook MetricsHook
+
+	// Clock is used to read the time and to wait between attempts, defaults to the real clock
+	Clock Clock
 }
 
 /*
 DefaultConfig initialize the default configuration
   - InitialDelay: default "3s"
   - MaxRetries: default "3"
+  - MaxAttempts: default "0"
   - BackoffType: default "constant"
   - MaxDuration: default "10s"
-  - Jitter: default "2.5s"
+  - Jitter: default "200ms"
+  - JitterMode: default "additive"
+  - JitterPercent: default "0"
+  - MaxDelay: default "0s"
+  - Multiplier: default "2"
 
 Notes:
-  - MaxDuration is used to set the maximum total amount of time that backoff should execute. List of BackoffType "fibonacci", "constant", "exponential"
+  - MaxAttempts sets the total number of fn invocations, "MaxRetries=3" means 4 invocations while "MaxAttempts=3" means 3. When set it overrides MaxRetries and a warning is logged if they disagree
+  - MaxDuration is used to set the maximum total amount of time that backoff should execute. List of BackoffType "fibonacci", "constant", "exponential", "decorrelated_jitter", "linear", "random"
+  - MaxDelay is used to cap the delay of a single attempt, unlike MaxDuration it does not stop the retry. With "exponential" the delays ramp up then stay at MaxDelay. To disable the cap, set MaxDelay to "0s"
   - Jitter is used to to reduce the changes of a thundering herd, add random jitter to the returned value
-  - To use infinity retry, set MaxDuration to "0s" and MaxRetries to "-1"
+  - JitterPercent computes the additive jitter as a percentage of the current delay, e.g. "10" for 10%. It can not be used together with Jitter, set Jitter to "0s" to use it
+  - List of JitterMode "additive" adds a random value between -Jitter and Jitter, "full" picks a random delay between 0 and the delay, "equal" picks a random delay between half the delay and the delay, "none" disables jitter
+  - DecorrelatedJitter picks a random delay between InitialDelay and 3 times the previous delay, capped by MaxDuration
+  - Multiplier is used by "exponential" to grow the delay on every attempt, a zero Multiplier uses "2"
+  - Linear grows the delay by InitialDelay on every attempt
+  - Random picks "constant", "exponential" or "fibonacci" once per run using RandSource or IdempotencyKey, e.g. for chaos testing. ResetBackoff picks again since the backoff is recreated
+  - An empty BackoffType uses "constant"
+  - InitialDelay must be greater than "0s" unless CustomBackoff or Schedule is set, a zero delay would retry in a busy loop
+  - CustomBackoff replaces BackoffType and InitialDelay, Jitter, MaxDelay, MaxDuration and MaxRetries still apply
+  - Schedule replaces BackoffType, InitialDelay and MaxRetries, the number of delays bounds the retries. Jitter, MaxDelay and MaxDuration still apply, set Jitter to "0s" to wait the exact delays
+  - To use infinity retry, set MaxDuration to "0s" and Infinite to "true". Setting MaxRetries to "-1" is deprecated but still retries infinitely
   - To disable jitter, set jitter to "0s"
+  - A zero Config uses the configuration set by SetDefaultConfig, or DefaultConfig when it is not set
+  - Retryable errors are matched with errors.Is, set MatchByMessage to "true" to also match errors with the same message
 */
 func DefaultConfig() Config {
 	return Config{
@@ -48,93 +197,1380 @@ func DefaultConfig() Config {
 		BackoffType:  Constant,
 		MaxDuration:  maxDuration,
 		Jitter:       jitter,
+		Multiplier:   multiplier,
+	}
+}
+
+/*
+DefaultConfigFor initialize the default configuration tuned for the backoff type
+  - InitialDelay: "3s" for "constant", "1s" for "linear", "500ms" for "exponential", "fibonacci", "decorrelated_jitter" and "random"
+
+Notes:
+  - The other values are the same as DefaultConfig
+  - An unknown backoff type keeps the InitialDelay of DefaultConfig and is rejected by Validate
+*/
+func DefaultConfigFor(t BackoffType) Config {
+	cfg := DefaultConfig()
+	cfg.BackoffType = t
+
+	switch t {
+	case Linear:
+		cfg.InitialDelay = time.Second
+	case Exponential, Fibonacci, DecorrelatedJitter, Random:
+		cfg.InitialDelay = 500 * time.Millisecond
 	}
+
+	return cfg
+}
+
+var (
+	defaultMu  sync.RWMutex
+	defaultCfg *Config
+)
+
+// SetDefaultConfig replaces the configuration used by the retries that receive a zero Config, it is safe for concurrent use
+func SetDefaultConfig(cfg Config) {
+	cfg = cfg.Clone()
+
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	defaultCfg = &cfg
+}
+
+// DefaultConfigValue returns the configuration set by SetDefaultConfig, or DefaultConfig when it is not set
+func DefaultConfigValue() Config {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+
+	if defaultCfg == nil {
+		return DefaultConfig()
+	}
+
+	return defaultCfg.Clone()
+}
+
+// resolveConfig returns DefaultConfigValue for a zero cfg, otherwise cfg
+func resolveConfig(cfg Config) Config {
+	if reflect.ValueOf(cfg).IsZero() {
+		return DefaultConfigValue()
+	}
+
+	return cfg
+}
+
+// ConfigPatch holds the values to be applied on a Config, nil fields are not provided and keep the existing value
+type ConfigPatch struct {
+	InitialDelay        *time.Duration
+	MaxRetries          *int
+	BackoffType         *BackoffType
+	Jitter              *time.Duration
+	JitterMode          *JitterMode
+	JitterPercent       *float64
+	MaxDuration         *time.Duration
+	MaxDelay            *time.Duration
+	FirstAttemptDelay   *time.Duration
+	AttemptTimeout      *time.Duration
+	MatchByMessage      *bool
+	RecoverPanics       *bool
+	RepanicOnGiveUp     *bool
+	Multiplier          *float64
+	UseContextDeadline  *bool
+	Disabled            *bool
+	IdempotencyKey      *string
+	MaxAttempts         *int
+	JoinErrors          *bool
+	RetryTemporary      *bool
+	JitterMaxFraction   *float64
+	Concurrency         *int
+	MinInterval         *time.Duration
+	ReturnSuccessError  *bool
+	RandomizeFirstDelay *bool
+	RetryOnlyListed     *bool
+	AbsoluteMaxCalls    *int
+	Infinite            *bool
+	EscalateOnRepeat    *bool
+	OnRetry             func(attempt int, err error, nextDelay time.Duration)
+	OnSuccess           func(attempts int, totalElapsed time.Duration)
+	OnGiveUp            func(attempts int, lastErr error)
+	RandSource          *rand.Rand
+	Logger              Logger
+	TraceHook           TraceHook
+	MetricsHook         MetricsHook
+	Clock               Clock
+	CustomBackoff       func(attempt int) time.Duration
+	SuccessErrors       []error
+	Schedule            []time.Duration
+	DelayFunc           func(attempt int, computed time.Duration) time.Duration
+	BeforeRetry         func(ctx context.Context, attempt int, err error) bool
+	OnFlaky             func(attempts int, errs []error)
+	JitterOnlyFor       []BackoffType
+	AdaptiveFunc        func(lastLatency time.Duration) time.Duration
 }
 
-// UpdateConfig updates the provided values without changing the existing configuration
+// Apply updates the provided values of the patch, including values explicitly set to zero
+func (c *Config) Apply(p ConfigPatch) {
+	if p.InitialDelay != nil {
+		c.InitialDelay = *p.InitialDelay
+	}
+	if p.MaxRetries != nil {
+		c.MaxRetries = *p.MaxRetries
+	}
+	if p.BackoffType != nil {
+		c.BackoffType = *p.BackoffType
+	}
+	if p.Jitter != nil {
+		c.Jitter = *p.Jitter
+	}
+	if p.JitterMode != nil {
+		c.JitterMode = *p.JitterMode
+	}
+	if p.JitterPercent != nil {
+		c.JitterPercent = *p.JitterPercent
+	}
+	if p.MaxDuration != nil {
+		c.MaxDuration = *p.MaxDuration
+	}
+	if p.MaxDelay != nil {
+		c.MaxDelay = *p.MaxDelay
+	}
+	if p.FirstAttemptDelay != nil {
+		c.FirstAttemptDelay = *p.FirstAttemptDelay
+	}
+	if p.AttemptTimeout != nil {
+		c.AttemptTimeout = *p.AttemptTimeout
+	}
+	if p.MatchByMessage != nil {
+		c.MatchByMessage = *p.MatchByMessage
+	}
+	if p.RecoverPanics != nil {
+		c.RecoverPanics = *p.RecoverPanics
+	}
+	if p.RepanicOnGiveUp != nil {
+		c.RepanicOnGiveUp = *p.RepanicOnGiveUp
+	}
+	if p.Multiplier != nil {
+		c.Multiplier = *p.Multiplier
+	}
+	if p.UseContextDeadline != nil {
+		c.UseContextDeadline = *p.UseContextDeadline
+	}
+	if p.Disabled != nil {
+		c.Disabled = *p.Disabled
+	}
+	if p.IdempotencyKey != nil {
+		c.IdempotencyKey = *p.IdempotencyKey
+	}
+	if p.MaxAttempts != nil {
+		c.MaxAttempts = *p.MaxAttempts
+	}
+	if p.JoinErrors != nil {
+		c.JoinErrors = *p.JoinErrors
+	}
+	if p.RetryTemporary != nil {
+		c.RetryTemporary = *p.RetryTemporary
+	}
+	if p.JitterMaxFraction != nil {
+		c.JitterMaxFraction = *p.JitterMaxFraction
+	}
+	if p.Concurrency != nil {
+		c.Concurrency = *p.Concurrency
+	}
+	if p.MinInterval != nil {
+		c.MinInterval = *p.MinInterval
+	}
+	if p.ReturnSuccessError != nil {
+		c.ReturnSuccessError = *p.ReturnSuccessError
+	}
+	if p.RandomizeFirstDelay != nil {
+		c.RandomizeFirstDelay = *p.RandomizeFirstDelay
+	}
+	if p.RetryOnlyListed != nil {
+		c.RetryOnlyListed = *p.RetryOnlyListed
+	}
+	if p.AbsoluteMaxCalls != nil {
+		c.AbsoluteMaxCalls = *p.AbsoluteMaxCalls
+	}
+	if p.Infinite != nil {
+		c.Infinite = *p.Infinite
+	}
+	if p.EscalateOnRepeat != nil {
+		c.EscalateOnRepeat = *p.EscalateOnRepeat
+	}
+	if p.OnRetry != nil {
+		c.OnRetry = p.OnRetry
+	}
+	if p.OnSuccess != nil {
+		c.OnSuccess = p.OnSuccess
+	}
+	if p.OnGiveUp != nil {
+		c.OnGiveUp = p.OnGiveUp
+	}
+	if p.RandSource != nil {
+		c.RandSource = p.RandSource
+	}
+	if p.Logger != nil {
+		c.Logger = p.Logger
+	}
+	if p.TraceHook != nil {
+		c.TraceHook = p.TraceHook
+	}
+	if p.MetricsHook != nil {
+		c.MetricsHook = p.MetricsHook
+	}
+	if p.Clock != nil {
+		c.Clock = p.Clock
+	}
+	if p.CustomBackoff != nil {
+		c.CustomBackoff = p.CustomBackoff
+	}
+	if p.SuccessErrors != nil {
+		c.SuccessErrors = p.SuccessErrors
+	}
+	if p.Schedule != nil {
+		c.Schedule = p.Schedule
+	}
+	if p.DelayFunc != nil {
+		c.DelayFunc = p.DelayFunc
+	}
+	if p.BeforeRetry != nil {
+		c.BeforeRetry = p.BeforeRetry
+	}
+	if p.OnFlaky != nil {
+		c.OnFlaky = p.OnFlaky
+	}
+	if p.JitterOnlyFor != nil {
+		c.JitterOnlyFor = p.JitterOnlyFor
+	}
+	if p.AdaptiveFunc != nil {
+		c.AdaptiveFunc = p.AdaptiveFunc
+	}
+}
+
+// UpdateConfig updates the provided values without changing the existing configuration, zero values are ignored
 func (c *Config) UpdateConfig(newConfig Config) {
+	var p ConfigPatch
 	if newConfig.InitialDelay != 0 {
-		c.InitialDelay = newConfig.InitialDelay
+		p.InitialDelay = &newConfig.InitialDelay
 	}
 	if newConfig.MaxRetries != 0 {
-		c.MaxRetries = newConfig.MaxRetries
+		p.MaxRetries = &newConfig.MaxRetries
 	}
 	if newConfig.BackoffType != "" {
-		c.BackoffType = newConfig.BackoffType
+		p.BackoffType = &newConfig.BackoffType
 	}
 	if newConfig.Jitter != 0 {
-		c.Jitter = newConfig.Jitter
+		p.Jitter = &newConfig.Jitter
+	}
+	if newConfig.JitterMode != "" {
+		p.JitterMode = &newConfig.JitterMode
+	}
+	if newConfig.JitterPercent != 0 {
+		p.JitterPercent = &newConfig.JitterPercent
 	}
 	if newConfig.MaxDuration != 0 {
-		c.MaxDuration = newConfig.MaxDuration
+		p.MaxDuration = &newConfig.MaxDuration
+	}
+	if newConfig.MaxDelay != 0 {
+		p.MaxDelay = &newConfig.MaxDelay
+	}
+	if newConfig.FirstAttemptDelay != 0 {
+		p.FirstAttemptDelay = &newConfig.FirstAttemptDelay
+	}
+	if newConfig.AttemptTimeout != 0 {
+		p.AttemptTimeout = &newConfig.AttemptTimeout
+	}
+	if newConfig.MatchByMessage {
+		p.MatchByMessage = &newConfig.MatchByMessage
+	}
+	if newConfig.RecoverPanics {
+		p.RecoverPanics = &newConfig.RecoverPanics
+	}
+	if newConfig.RepanicOnGiveUp {
+		p.RepanicOnGiveUp = &newConfig.RepanicOnGiveUp
+	}
+	if newConfig.Multiplier != 0 {
+		p.Multiplier = &newConfig.Multiplier
+	}
+	if newConfig.UseContextDeadline {
+		p.UseContextDeadline = &newConfig.UseContextDeadline
+	}
+	if newConfig.Disabled {
+		p.Disabled = &newConfig.Disabled
+	}
+	if newConfig.IdempotencyKey != "" {
+		p.IdempotencyKey = &newConfig.IdempotencyKey
+	}
+	if newConfig.MaxAttempts != 0 {
+		p.MaxAttempts = &newConfig.MaxAttempts
+	}
+	if newConfig.JoinErrors {
+		p.JoinErrors = &newConfig.JoinErrors
 	}
+	if newConfig.RetryTemporary {
+		p.RetryTemporary = &newConfig.RetryTemporary
+	}
+	if newConfig.JitterMaxFraction != 0 {
+		p.JitterMaxFraction = &newConfig.JitterMaxFraction
+	}
+	if newConfig.Concurrency != 0 {
+		p.Concurrency = &newConfig.Concurrency
+	}
+	if newConfig.MinInterval != 0 {
+		p.MinInterval = &newConfig.MinInterval
+	}
+	if newConfig.ReturnSuccessError {
+		p.ReturnSuccessError = &newConfig.ReturnSuccessError
+	}
+	if newConfig.RandomizeFirstDelay {
+		p.RandomizeFirstDelay = &newConfig.RandomizeFirstDelay
+	}
+	if newConfig.RetryOnlyListed {
+		p.RetryOnlyListed = &newConfig.RetryOnlyListed
+	}
+	if newConfig.AbsoluteMaxCalls != 0 {
+		p.AbsoluteMaxCalls = &newConfig.AbsoluteMaxCalls
+	}
+	if newConfig.Infinite {
+		p.Infinite = &newConfig.Infinite
+	}
+	if newConfig.EscalateOnRepeat {
+		p.EscalateOnRepeat = &newConfig.EscalateOnRepeat
+	}
+	p.OnRetry = newConfig.OnRetry
+	p.OnSuccess = newConfig.OnSuccess
+	p.OnGiveUp = newConfig.OnGiveUp
+	p.RandSource = newConfig.RandSource
+	p.Logger = newConfig.Logger
+	p.TraceHook = newConfig.TraceHook
+	p.MetricsHook = newConfig.MetricsHook
+	p.Clock = newConfig.Clock
+	p.CustomBackoff = newConfig.CustomBackoff
+	p.SuccessErrors = newConfig.SuccessErrors
+	p.Schedule = newConfig.Schedule
+	p.DelayFunc = newConfig.DelayFunc
+	p.BeforeRetry = newConfig.BeforeRetry
+	p.OnFlaky = newConfig.OnFlaky
+	p.JitterOnlyFor = newConfig.JitterOnlyFor
+	p.AdaptiveFunc = newConfig.AdaptiveFunc
+
+	c.Apply(p)
 }
 
-// DoRetry will perform a retry by entering a list of errors that need to be retried
-func DoRetry(ctx context.Context, cfg Config, fn func(context.Context) error, retryableError []error) error {
+// MergeConfigs returns base updated with the non-zero values of every override in order, like UpdateConfig the later overrides take precedence
+func MergeConfigs(base Config, overrides ...Config) Config {
+	cfg := base.Clone()
+	for _, v := range overrides {
+		cfg.UpdateConfig(v)
+	}
+
+	return cfg
+}
+
+// Clone returns a copy of the configuration that can be updated without changing c, slices are copied while callbacks, hooks and RandSource are shared
+func (c Config) Clone() Config {
+	clone := c
+	clone.SuccessErrors = append([]error(nil), c.SuccessErrors...)
+	clone.Schedule = append([]time.Duration(nil), c.Schedule...)
+	clone.JitterOnlyFor = append([]BackoffType(nil), c.JitterOnlyFor...)
+
+	return clone
+}
+
+// Validate returns an error describing the first invalid value of the configuration
+func (c Config) Validate() error {
+	if c.MaxRetries < -1 {
+		return fmt.Errorf("%w: MaxRetries must not be less than -1, got %d", ErrInvalidConfig, c.MaxRetries)
+	}
+	if c.Infinite && c.MaxAttempts > 0 {
+		return fmt.Errorf("%w: Infinite and MaxAttempts can not be used together", ErrInvalidConfig)
+	}
+	if c.MaxAttempts < 0 {
+		return fmt.Errorf("%w: MaxAttempts must not be negative, got %d", ErrInvalidConfig, c.MaxAttempts)
+	}
+	if c.AbsoluteMaxCalls < 0 {
+		return fmt.Errorf("%w: AbsoluteMaxCalls must not be negative, got %d", ErrInvalidConfig, c.AbsoluteMaxCalls)
+	}
+	if c.InitialDelay < 0 {
+		return fmt.Errorf("%w: InitialDelay must not be negative, got %s", ErrInvalidConfig, c.InitialDelay)
+	}
+	if c.InitialDelay == 0 && c.CustomBackoff == nil && len(c.Schedule) == 0 {
+		return fmt.Errorf("%w: InitialDelay must be greater than 0", ErrInvalidConfig)
+	}
+	if c.Jitter < 0 {
+		return fmt.Errorf("%w: Jitter must not be negative, got %s", ErrInvalidConfig, c.Jitter)
+	}
+	if c.JitterPercent < 0 || c.JitterPercent > 100 {
+		return fmt.Errorf("%w: JitterPercent must be between 0 and 100, got %v", ErrInvalidConfig, c.JitterPercent)
+	}
+	if c.Jitter > 0 && c.JitterPercent > 0 {
+		return fmt.Errorf("%w: Jitter and JitterPercent can not be used together", ErrInvalidConfig)
+	}
+	if c.JitterMaxFraction < 0 || c.JitterMaxFraction > 1 {
+		return fmt.Errorf("%w: JitterMaxFraction must be between 0 and 1, got %v", ErrInvalidConfig, c.JitterMaxFraction)
+	}
+	if c.MaxDuration < 0 {
+		return fmt.Errorf("%w: MaxDuration must not be negative, got %s", ErrInvalidConfig, c.MaxDuration)
+	}
+	if c.MaxDelay < 0 {
+		return fmt.Errorf("%w: MaxDelay must not be negative, got %s", ErrInvalidConfig, c.MaxDelay)
+	}
+	if c.Multiplier != 0 && c.Multiplier < 1 {
+		return fmt.Errorf("%w: Multiplier must be at least 1, got %v", ErrInvalidConfig, c.Multiplier)
+	}
+	if c.MinInterval < 0 {
+		return fmt.Errorf("%w: MinInterval must not be negative, got %s", ErrInvalidConfig, c.MinInterval)
+	}
+	if c.MaxDelay > 0 && c.MinInterval > c.MaxDelay {
+		return fmt.Errorf("%w: MinInterval %s must not exceed MaxDelay %s", ErrInvalidConfig, c.MinInterval, c.MaxDelay)
+	}
+	if c.FirstAttemptDelay < 0 {
+		return fmt.Errorf("%w: FirstAttemptDelay must not be negative, got %s", ErrInvalidConfig, c.FirstAttemptDelay)
+	}
+	if c.Concurrency < 0 {
+		return fmt.Errorf("%w: Concurrency must not be negative, got %d", ErrInvalidConfig, c.Concurrency)
+	}
+	if c.AttemptTimeout < 0 {
+		return fmt.Errorf("%w: AttemptTimeout must not be negative, got %s", ErrInvalidConfig, c.AttemptTimeout)
+	}
+
+	if c.CustomBackoff != nil && len(c.Schedule) > 0 {
+		return fmt.Errorf("%w: CustomBackoff and Schedule can not be used together", ErrInvalidConfig)
+	}
+	for i, v := range c.Schedule {
+		if v < 0 {
+			return fmt.Errorf("%w: Schedule[%d] must not be negative, got %s", ErrInvalidConfig, i, v)
+		}
+	}
+
+	for _, v := range append([]BackoffType{c.BackoffType}, c.JitterOnlyFor...) {
+		switch v {
+		case "", Constant, Exponential, Fibonacci, DecorrelatedJitter, Linear, Random:
+		default:
+			return fmt.Errorf("%w: unknown BackoffType %q", ErrInvalidConfig, v)
+		}
+	}
+
+	switch c.JitterMode {
+	case "", JitterAdditive, JitterFull, JitterEqual, JitterNone:
+	default:
+		return fmt.Errorf("%w: unknown JitterMode %q", ErrInvalidConfig, c.JitterMode)
+	}
+
+	return nil
+}
+
+/*
+PreviewDelays returns the first n delays the configured backoff would wait between attempts
+
+Notes:
+  - Jitter is not applied, the delays of "decorrelated_jitter" are still random
+  - MaxDuration is applied assuming fn returns immediately
+  - Fewer than n delays are returned when MaxRetries or MaxDuration stops the backoff, nil is returned for an invalid config
+*/
+func (c Config) PreviewDelays(n int) []time.Duration {
+	if c.Validate() != nil {
+		return nil
+	}
+
+	cfg := c
+	cfg.JitterMode = JitterNone
+	cfg.MaxDuration = 0
 	b := getBackoff(cfg)
 
-	fn2 := func() func(ctx context.Context) error {
-		return func(ctx context.Context) error {
-			err := fn(ctx)
+	var elapsed time.Duration
+	delays := make([]time.Duration, 0, n)
+	for len(delays) < n {
+		d, stop := b.Next()
+		if stop {
+			break
+		}
 
-			if err == nil {
-				return nil
+		if c.MaxDuration > 0 {
+			remaining := c.MaxDuration - elapsed
+			if remaining <= 0 {
+				break
+			}
+			if d > remaining {
+				d = remaining
 			}
+		}
 
-			if len(retryableError) > 0 {
-				for _, v := range retryableError {
-					if err.Error() == v.Error() {
-						err = pkgRetry.RetryableError(v)
-					}
-				}
+		elapsed += d
+		delays = append(delays, d)
+	}
+
+	return delays
+}
+
+/*
+PreviewBackoff returns the first attempts delays of the configured backoff type without any jitter, limit or sleep
+
+Notes:
+  - MaxRetries, MaxDuration, MaxDelay and MinInterval are not applied, use PreviewDelays to preview the delays actually waited
+  - "decorrelated_jitter" returns the upper bound of its random delays, capped by MaxDuration
+  - Fewer delays are returned when Schedule ends, nil is returned for an invalid config
+*/
+func PreviewBackoff(cfg Config, attempts int) []time.Duration {
+	if attempts <= 0 || cfg.Validate() != nil {
+		return nil
+	}
+
+	var next func() (time.Duration, bool)
+	if cfg.BackoffType == DecorrelatedJitter && cfg.CustomBackoff == nil && len(cfg.Schedule) == 0 {
+		prev := cfg.InitialDelay
+		next = func() (time.Duration, bool) {
+			if prev < math.MaxInt64/3 {
+				prev *= 3
+			} else {
+				prev = math.MaxInt64
+			}
+			if cfg.MaxDuration > 0 && prev > cfg.MaxDuration {
+				prev = cfg.MaxDuration
 			}
 
-			return err
+			return prev, false
+		}
+	} else {
+		next = baseBackoff(cfg, getRand(cfg)).Next
+	}
+
+	delays := make([]time.Duration, 0, attempts)
+	for len(delays) < attempts {
+		d, stop := next()
+		if stop {
+			break
+		}
+
+		delays = append(delays, d)
+	}
+
+	return delays
+}
+
+/*
+DoRetry will perform a retry by entering a list of errors that need to be retried
+
+Notes:
+  - When all retries are used up, the last error is returned wrapped in *RetriesExhaustedError, no delay is waited after the last attempt
+  - When UseContextDeadline stops the retry, the last error is returned wrapped in *RetriesExhaustedError
+  - When MaxDuration stops the retry, the last error is returned wrapped in *MaxDurationExceededError
+  - When the error implements RetryAfterError, the next delay is at least the suggested delay capped by MaxDelay. A suggested delay longer than the time left in MaxDuration stops the retry with *MaxDurationExceededError
+  - Errors marked with PermanentError stop the retry immediately and are returned unwrapped
+  - Errors marked with ResetBackoff are retried with a new backoff, the delays, MaxRetries and MaxDuration start again from the beginning
+  - When the context is done before the retry finishes, the context error and the last error are returned wrapped in *ContextError
+  - The delays are interrupted as soon as the context is done, the remaining delay is not waited
+  - MaxDuration is capped by the time left until the deadline of the context, the retry never waits past the deadline. With UseContextDeadline the retry stops before a delay that would cross the deadline instead of shortening it
+  - An empty retryableError does not retry plain errors, only marked errors, attempt timeouts and temporary errors (with RetryTemporary) are retried. Set RetryOnlyListed to "true" to fail fast on those too
+*/
+func DoRetry(ctx context.Context, cfg Config, fn func(context.Context) error, retryableError []error) error {
+	_, err := DoRetryWithResult(ctx, cfg, noResult(fn), retryableError)
+
+	return err
+}
+
+// DoSimple will perform a retry like DoRetry for a function without context, ctx is still used to stop between attempts
+func DoSimple(ctx context.Context, cfg Config, fn func() error, retryableError []error) error {
+	return DoRetry(ctx, cfg, func(context.Context) error {
+		return fn()
+	}, retryableError)
+}
+
+// DoRetryForever will perform a retry like DoRetry ignoring MaxRetries, MaxAttempts and MaxDuration, it retries until fn succeeds, returns a non retryable error or ctx is done
+func DoRetryForever(ctx context.Context, cfg Config, fn func(context.Context) error, retryableError []error) error {
+	cfg = resolveConfig(cfg)
+	cfg.Infinite = true
+	cfg.MaxAttempts = 0
+	cfg.MaxDuration = 0
+
+	return DoRetry(ctx, cfg, fn, retryableError)
+}
+
+// DoRetryWithStop will perform a retry like DoRetry and stop immediately with ErrStopped when stop is closed
+func DoRetryWithStop(ctx context.Context, cfg Config, fn func(context.Context) error, retryableError []error, stop <-chan struct{}) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	go func() {
+		select {
+		case <-stop:
+			cancel(ErrStopped)
+		case <-ctx.Done():
+		}
+	}()
+
+	return DoRetry(ctx, cfg, fn, retryableError)
+}
+
+// DoRetryCancelable will start a retry like DoRetry in a goroutine, the result is delivered on done and cancel stops the retry with ErrStopped
+func DoRetryCancelable(ctx context.Context, cfg Config, fn func(context.Context) error, retryableError []error) (<-chan error, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	done := make(chan error, 1)
+
+	go func() {
+		defer cancel(nil)
+		done <- DoRetry(ctx, cfg, fn, retryableError)
+	}()
+
+	return done, func() {
+		cancel(ErrStopped)
+	}
+}
+
+// DoRetryCount will perform a retry like DoRetry and return the number of times fn was invoked
+func DoRetryCount(ctx context.Context, cfg Config, fn func(context.Context) error, retryableError []error) (int, error) {
+	cfg = resolveConfig(cfg)
+	_, attempts, err := doRetry(ctx, cfg, noResult(fn), func(err error) bool {
+		return isRetryableError(cfg, err, retryableError)
+	})
+
+	return attempts, err
+}
+
+// DoRetryCollect will perform a retry like DoRetry and return the errors of every failed attempt in order
+func DoRetryCollect(ctx context.Context, cfg Config, fn func(context.Context) error, retryableError []error) ([]error, error) {
+	var errs []error
+	err := DoRetry(ctx, cfg, func(ctx context.Context) error {
+		err := fn(ctx)
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		return err
+	}, retryableError)
+
+	return errs, err
+}
+
+// DoRetryWithResult will perform a retry like DoRetry and return the value produced by fn, on failure the zero value is returned
+func DoRetryWithResult[T any](ctx context.Context, cfg Config, fn func(context.Context) (T, error), retryableError []error) (T, error) {
+	cfg = resolveConfig(cfg)
+	v, _, err := doRetry(ctx, cfg, fn, func(err error) bool {
+		return isRetryableError(cfg, err, retryableError)
+	})
+
+	return v, err
+}
+
+// DoRetryWithResultCount will perform a retry like DoRetryWithResult and return the number of times fn was invoked
+func DoRetryWithResultCount[T any](ctx context.Context, cfg Config, fn func(context.Context) (T, error), retryableError []error) (T, int, error) {
+	cfg = resolveConfig(cfg)
+	return doRetry(ctx, cfg, fn, func(err error) bool {
+		return isRetryableError(cfg, err, retryableError)
+	})
+}
+
+// DoRetryWithFallback will perform a retry like DoRetryWithResult and call fallback once when the retries are exhausted, a fallback error is joined with the exhausted error
+func DoRetryWithFallback[T any](ctx context.Context, cfg Config, fn func(context.Context) (T, error), fallback func(context.Context) (T, error), retryableError []error) (T, error) {
+	v, err := DoRetryWithResult(ctx, cfg, fn, retryableError)
+
+	var exhaustedErr *RetriesExhaustedError
+	var maxDurationErr *MaxDurationExceededError
+	if !errors.As(err, &exhaustedErr) && !errors.As(err, &maxDurationErr) {
+		return v, err
+	}
+
+	v, fallbackErr := fallback(ctx)
+	if fallbackErr != nil {
+		return v, errors.Join(err, fallbackErr)
+	}
+
+	return v, nil
+}
+
+// DoRetryArg will perform a retry like DoRetryWithResult, arg is passed to fn on every attempt
+func DoRetryArg[A, R any](ctx context.Context, cfg Config, arg A, fn func(context.Context, A) (R, error), retryableError []error) (R, error) {
+	return DoRetryWithResult(ctx, cfg, func(ctx context.Context) (R, error) {
+		return fn(ctx, arg)
+	}, retryableError)
+}
+
+/*
+DoRetryWithTypes will perform a retry by entering a list of error types that need to be retried
+
+Notes:
+  - Each target must be a non-nil pointer to an error type, e.g. "new(*net.OpError)", it is used with errors.As
+  - The matched error is assigned to the target, so targets should not be shared between concurrent calls
+  - DoRetryWithTypes only matches by type, use DoRetry to match errors by value
+*/
+func DoRetryWithTypes(ctx context.Context, cfg Config, fn func(context.Context) error, targets []any) error {
+	_, _, err := doRetry(ctx, cfg, noResult(fn), func(err error) bool {
+		return isRetryableType(err, targets)
+	})
+
+	return err
+}
+
+// DoRetryIf will perform a retry when shouldRetry reports true for the error, a nil shouldRetry retries on any error
+func DoRetryIf(ctx context.Context, cfg Config, fn func(context.Context) error, shouldRetry func(error) bool) error {
+	if shouldRetry == nil {
+		shouldRetry = func(error) bool {
+			return true
 		}
 	}
 
-	return pkgRetry.Do(ctx, b, fn2())
+	_, _, err := doRetry(ctx, cfg, noResult(fn), shouldRetry)
+
+	return err
+}
+
+// DoRetryResultIf will perform a retry when shouldRetry reports true for the result and the error of fn, a rejected result with a nil error fails with ErrResultRejected.
+// A nil shouldRetry retries on any error
+func DoRetryResultIf[T any](ctx context.Context, cfg Config, fn func(context.Context) (T, error), shouldRetry func(T, error) bool) (T, error) {
+	if shouldRetry == nil {
+		shouldRetry = func(_ T, err error) bool {
+			return err != nil
+		}
+	}
+
+	var retry bool
+	v, _, err := doRetry(ctx, cfg, func(ctx context.Context) (T, error) {
+		v, err := fn(ctx)
+		retry = shouldRetry(v, err)
+		if retry && err == nil {
+			err = ErrResultRejected
+		}
+
+		return v, err
+	}, func(error) bool {
+		return retry
+	})
+
+	return v, err
+}
+
+// DoRetryMatch will perform a retry when one of the matchers reports true for the error, use MatchError to match an error value
+func DoRetryMatch(ctx context.Context, cfg Config, fn func(context.Context) error, matchers []func(error) bool) error {
+	_, _, err := doRetry(ctx, cfg, noResult(fn), func(err error) bool {
+		for _, match := range matchers {
+			if match != nil && match(err) {
+				return true
+			}
+		}
+
+		return false
+	})
+
+	return err
+}
+
+// MatchError returns a matcher reporting whether the error matches target with errors.Is
+func MatchError(target error) func(error) bool {
+	return func(err error) bool {
+		return errors.Is(err, target)
+	}
 }
 
 // DoRetryWithCustomRetryableError will perform a retry by implementing **RetryableError** on the error to be retried
 func DoRetryWithCustomRetryableError(ctx context.Context, cfg Config, fn pkgRetry.RetryFunc) error {
-	b := getBackoff(cfg)
-	err := pkgRetry.Do(ctx, b, fn)
+	return DoRetryCustom(ctx, cfg, fn)
+}
+
+// DoRetryCustom will perform a retry like DoRetryWithCustomRetryableError, errors marked with RetryableError or ResetBackoff are retried.
+// Like DoRetry with an empty retryableError, attempt timeouts and temporary errors (with RetryTemporary) are retried too, set RetryOnlyListed to "true" to only retry the marked errors
+func DoRetryCustom(ctx context.Context, cfg Config, fn func(context.Context) error) error {
+	_, _, err := doRetry(ctx, cfg, noResult(fn), func(err error) bool {
+		return false
+	})
 
 	return err
 }
 
-// RetryableError marks an error as retryable
+// RetryableError marks an error as retryable, a nil error returns nil
 func RetryableError(err error) error {
+	if err == nil {
+		return nil
+	}
+
 	return pkgRetry.RetryableError(err)
 }
 
-// Set config backoff
-func getBackoff(cfg Config) pkgRetry.Backoff {
-	var b pkgRetry.Backoff
+// IsRetryable reports whether err or an error wrapped by it is marked with RetryableError
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	_, ok := unwrapRetryable(err)
+
+	return ok
+}
+
+// isRetryableError reports whether err matches one of the retryable errors
+func isRetryableError(cfg Config, err error, retryableError []error) bool {
+	for _, v := range retryableError {
+		if v == nil {
+			continue
+		}
+		if errors.Is(err, v) {
+			return true
+		}
+		if cfg.MatchByMessage && err.Error() == v.Error() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesError reports whether err matches one of errs with errors.Is
+func matchesError(err error, errs []error) bool {
+	for _, v := range errs {
+		if errors.Is(err, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isRetryableType reports whether err matches one of the retryable error types
+func isRetryableType(err error, targets []any) bool {
+	for _, v := range targets {
+		if errors.As(err, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rootError returns the innermost error of the chain of err, an error wrapping several errors is returned as is
+func rootError(err error) error {
+	for {
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			return err
+		}
+		err = inner
+	}
+}
+
+// escalate doubles d for every repeat, the result is capped by maxDelay when greater than 0
+func escalate(d time.Duration, repeats int, maxDelay time.Duration) time.Duration {
+	for i := 0; i < repeats && d < math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	if maxDelay > 0 && d > maxDelay {
+		d = maxDelay
+	}
+
+	return d
+}
+
+// isTemporary reports whether err implements "Temporary() bool" and returns true
+func isTemporary(err error) bool {
+	var t interface{ Temporary() bool }
+	return errors.As(err, &t) && t.Temporary()
+}
+
+// noResult adapts fn to return an empty result
+func noResult(fn func(context.Context) error) func(context.Context) (struct{}, error) {
+	return func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	}
+}
+
+// isAttemptTimeout reports whether err is caused by the attempt timeout and not by the parent context
+func isAttemptTimeout(ctx, attemptCtx context.Context, err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) && attemptCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+}
+
+// call invokes fn, a panic is returned as *PanicError when recoverPanics is true
+func call[T any](ctx context.Context, recoverPanics bool, fn func(context.Context) (T, error)) (v T, err error) {
+	if recoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				err = &PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
+	}
+
+	return fn(ctx)
+}
+
+// doRetry performs the retry and returns the number of attempts, errors reported by isRetryable are marked as retryable
+func doRetry[T any](ctx context.Context, cfg Config, fn func(context.Context) (T, error), isRetryable func(error) bool) (T, int, error) {
+	return doRetryBackoff(ctx, cfg, getBackoff, fn, isRetryable)
+}
+
+// doRetryBackoff performs the retry like doRetry with the backoff created by newBackoff
+func doRetryBackoff[T any](ctx context.Context, cfg Config, newBackoff func(Config) pkgRetry.Backoff, fn func(context.Context) (T, error), isRetryable func(error) bool) (T, int, error) {
+	cfg = resolveConfig(cfg)
+	if cfg.Disabled {
+		v, err := fn(withAttempt(ctx, 1, true))
+		if err != nil {
+			err = unmarkError(err)
+		}
+		return v, 1, err
+	}
+
+	var zero T
+	if err := cfg.Validate(); err != nil {
+		return zero, 0, err
+	}
+
+	clock := getClock(cfg)
+	if cfg.FirstAttemptDelay > 0 {
+		if err := clock.Sleep(ctx, cfg.FirstAttemptDelay); err != nil {
+			return zero, 0, &ContextError{Err: context.Cause(ctx)}
+		}
+	}
+
+	var (
+		v          T
+		err        error
+		lastErr    error
+		errs       []error
+		successErr error
+		prevErr    error
+		repeats    int
+		exhausted  bool
+		overLimit  bool
+		ctxDone    bool
+	)
+	logger := getLogger(cfg)
+	if cfg.MaxAttempts > 0 && cfg.MaxRetries != 0 && cfg.MaxRetries != cfg.MaxAttempts-1 {
+		logger.Warnf("goretry: MaxAttempts %d overrides MaxRetries %d", cfg.MaxAttempts, cfg.MaxRetries)
+	}
+
+	start := clock.Now()
+	attempts := 0
+	limit, resetAt := attemptLimit(cfg), 0
+	bcfg := withDeadline(ctx, cfg)
+	b, bStart := newBackoff(bcfg), clock.Now()
+
+	for {
+		if ctx.Err() != nil {
+			ctxDone = true
+			break
+		}
+
+		attempts++
+
+		var retryable, reset bool
+		attemptStart := clock.Now()
+		last := (limit > 0 && attempts == resetAt+limit) || (cfg.AbsoluteMaxCalls > 0 && attempts == cfg.AbsoluteMaxCalls)
+		v, retryable, reset, err = doAttempt(ctx, cfg, attempts, last, fn, isRetryable)
+		latency := clock.Now().Sub(attemptStart)
+		if err != nil && matchesError(err, cfg.SuccessErrors) {
+			successErr = err
+			err = nil
+		}
+		lastErr = err
+		if err != nil && (cfg.JoinErrors || cfg.OnFlaky != nil) {
+			errs = append(errs, err)
+		}
+		if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			ctxDone = true
+			break
+		}
+		if err == nil || !retryable {
+			break
+		}
+		if reset {
+			bcfg = withDeadline(ctx, cfg)
+			b, bStart = newBackoff(bcfg), clock.Now()
+			resetAt = attempts - 1
+		}
+
+		if cfg.AbsoluteMaxCalls > 0 && attempts >= cfg.AbsoluteMaxCalls {
+			exhausted = true
+			break
+		}
+
+		next, stop := b.Next()
+		if stop {
+			exhausted = true
+			break
+		}
+
+		if cfg.EscalateOnRepeat {
+			if prevErr != nil && errors.Is(rootError(err), prevErr) {
+				repeats++
+			} else {
+				repeats = 0
+			}
+			next = escalate(next, repeats, cfg.MaxDelay)
+		}
+		prevErr = rootError(err)
+
+		var retryAfterErr RetryAfterError
+		if errors.As(err, &retryAfterErr) && retryAfterErr.RetryAfter() > next {
+			next = retryAfterErr.RetryAfter()
+			if cfg.MaxDelay > 0 && next > cfg.MaxDelay {
+				next = cfg.MaxDelay
+			}
+			if left, ok := timeLeft(bcfg, clock, bStart); ok && next > left {
+				if cfg.MaxDuration > 0 {
+					// waiting as suggested would outlast MaxDuration
+					exhausted, overLimit = true, true
+					break
+				}
+				next = left
+			}
+		}
+
+		if cfg.AdaptiveFunc != nil {
+			if next += cfg.AdaptiveFunc(latency); next < 0 {
+				next = 0
+			}
+			// like the backoff delays, the adapted delay stays within MaxDelay and the time left in MaxDuration
+			if cfg.MaxDelay > 0 && next > cfg.MaxDelay {
+				next = cfg.MaxDelay
+			}
+			if left, ok := timeLeft(bcfg, clock, bStart); ok && next > left {
+				next = left
+			}
+		}
+
+		if cfg.DelayFunc != nil {
+			if next = cfg.DelayFunc(attempts, next); next < 0 {
+				next = 0
+			}
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && cfg.UseContextDeadline && clock.Now().Add(next).After(deadline) {
+			exhausted = true
+			break
+		}
+
+		if cfg.BeforeRetry != nil && !cfg.BeforeRetry(ctx, attempts, err) {
+			break
+		}
+
+		if g, ok := b.(retryGate); ok && !g.allowRetry() {
+			exhausted = true
+			break
+		}
+
+		logger.Debugf("goretry: attempt %d failed, retrying in %s: %v", attempts, next, err)
+		if cfg.MetricsHook != nil {
+			cfg.MetricsHook.ObserveDelay(next)
+		}
+		// next is final here, the hooks report the same delay that is waited
+		if cfg.OnRetry != nil {
+			cfg.OnRetry(attempts, err, next)
+		}
+
+		if clock.Sleep(ctx, next) != nil {
+			ctxDone = true
+			break
+		}
+	}
+
+	if err != nil && cfg.JoinErrors && len(errs) > 1 {
+		err = errors.Join(errs...)
+		lastErr = err
+	}
+
+	if ctxDone {
+		err = &ContextError{Attempts: attempts, Err: context.Cause(ctx), LastErr: lastErr}
+	}
+
+	var panicErr *PanicError
+	if cfg.RepanicOnGiveUp && !ctxDone && errors.As(err, &panicErr) {
+		panic(panicErr.Value)
+	}
+
+	if err == nil {
+		logger.Debugf("goretry: succeeded after %d attempts", attempts)
+	} else {
+		v = zero
+		logger.Warnf("goretry: failed after %d attempts: %v", attempts, err)
+	}
+
+	if cfg.MetricsHook != nil {
+		cfg.MetricsHook.ObserveOutcome(err == nil, attempts)
+	}
+
+	elapsed := clock.Now().Sub(start)
+	if err == nil && cfg.OnSuccess != nil {
+		cfg.OnSuccess(attempts, elapsed)
+	}
+	if err == nil && len(errs) > 0 && cfg.OnFlaky != nil {
+		cfg.OnFlaky(attempts, errs)
+	}
+
+	if err == nil && cfg.ReturnSuccessError {
+		err = successErr
+	}
+
+	if exhausted {
+		if cfg.OnGiveUp != nil {
+			cfg.OnGiveUp(attempts, err)
+		}
+		if overLimit || (cfg.MaxDuration > 0 && elapsed >= cfg.MaxDuration) {
+			err = &MaxDurationExceededError{Attempts: attempts, MaxDuration: cfg.MaxDuration, Elapsed: elapsed, Err: err}
+		} else {
+			err = &RetriesExhaustedError{Attempts: attempts, Elapsed: elapsed, Err: err}
+		}
+	}
+
+	return v, attempts, err
+}
+
+// retryGate is implemented by a backoff that must approve every retry, allowRetry is called once nothing else can stop the retry
+type retryGate interface {
+	allowRetry() bool
+}
+
+// timeLeft returns the time left in the MaxDuration of cfg for a backoff created at start, it reports false when MaxDuration is not set
+func timeLeft(cfg Config, clock Clock, start time.Time) (time.Duration, bool) {
+	if cfg.MaxDuration <= 0 {
+		return 0, false
+	}
+
+	left := cfg.MaxDuration - clock.Now().Sub(start)
+	if left < 0 {
+		left = 0
+	}
+
+	return left, true
+}
+
+// withDeadline returns cfg with MaxDuration capped by the time left until the deadline of ctx.
+// With UseContextDeadline the delay is not capped, the retry stops instead when the delay would cross the deadline
+func withDeadline(ctx context.Context, cfg Config) Config {
+	deadline, ok := ctx.Deadline()
+	if !ok || cfg.UseContextDeadline {
+		return cfg
+	}
+
+	remaining := deadline.Sub(getClock(cfg).Now())
+	if remaining <= 0 {
+		remaining = time.Nanosecond
+	}
+	if cfg.MaxDuration == 0 || remaining < cfg.MaxDuration {
+		cfg.MaxDuration = remaining
+	}
+
+	return cfg
+}
+
+// doAttempt invokes fn once and reports whether its error should be retried and whether the backoff should be restarted
+func doAttempt[T any](ctx context.Context, cfg Config, attempt int, last bool, fn func(context.Context) (T, error), isRetryable func(error) bool) (T, bool, bool, error) {
+	attemptCtx := withAttempt(ctx, attempt, last)
+	if cfg.AttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(attemptCtx, cfg.AttemptTimeout)
+		defer cancel()
+	}
+
+	var endAttempt func(error)
+	if cfg.TraceHook != nil {
+		attemptCtx, endAttempt = cfg.TraceHook.StartAttempt(attemptCtx, attempt)
+	}
+
+	v, err := call(attemptCtx, cfg.RecoverPanics, fn)
+	if endAttempt != nil {
+		endAttempt(err)
+	}
+	if cfg.MetricsHook != nil {
+		cfg.MetricsHook.ObserveAttempt(err)
+	}
+
+	if err == nil {
+		return v, false, false, nil
+	}
+
+	var perr *permanentError
+	if errors.As(err, &perr) {
+		return v, false, false, perr.err
+	}
+
+	var rerr *resetError
+	if errors.As(err, &rerr) {
+		return v, !cfg.RetryOnlyListed || isRetryable(rerr.err), true, rerr.err
+	}
+
+	if inner, ok := unwrapRetryable(err); ok {
+		return v, !cfg.RetryOnlyListed || isRetryable(inner), false, inner
+	}
+
+	if cfg.RetryOnlyListed {
+		return v, isRetryable(err), false, err
+	}
+
+	return v, isRetryable(err) || isAttemptTimeout(ctx, attemptCtx, err) || (cfg.RetryTemporary && isTemporary(err)), false, err
+}
+
+// unmarkError returns err without the PermanentError, ResetBackoff or RetryableError marker
+func unmarkError(err error) error {
+	var perr *permanentError
+	if errors.As(err, &perr) {
+		return perr.err
+	}
+
+	var rerr *resetError
+	if errors.As(err, &rerr) {
+		return rerr.err
+	}
+
+	if inner, ok := unwrapRetryable(err); ok {
+		return inner
+	}
+
+	return err
+}
+
+// unwrapRetryable reports whether err is marked with RetryableError and returns the marked error
+func unwrapRetryable(err error) (error, bool) {
+	marked := false
+	inner := pkgRetry.Do(context.Background(), pkgRetry.BackoffFunc(func() (time.Duration, bool) {
+		marked = true
+		return 0, true
+	}), func(context.Context) error {
+		return err
+	})
+
+	return inner, marked
+}
+
+// getRand returns the random source used for the jitter, it is safe for concurrent use
+func getRand(cfg Config) *rand.Rand {
+	if cfg.RandSource != nil {
+		return rand.New(&lockedSource{mu: &randSourceMu, src: cfg.RandSource})
+	}
+
+	seed := time.Now().UnixNano()
+	if cfg.IdempotencyKey != "" {
+		h := fnv.New64a()
+		h.Write([]byte(cfg.IdempotencyKey))
+		seed = int64(h.Sum64())
+	}
+
+	return rand.New(&lockedSource{mu: new(sync.Mutex), src: rand.NewSource(seed)})
+}
+
+// baseBackoff creates the backoff of the configured type without any limit or jitter
+func baseBackoff(cfg Config, r *rand.Rand) pkgRetry.Backoff {
+	if cfg.CustomBackoff != nil {
+		return newCustomBackoff(cfg.CustomBackoff)
+	}
+	if len(cfg.Schedule) > 0 {
+		return newSchedule(cfg.Schedule)
+	}
+
 	switch cfg.BackoffType {
-	case Constant:
-		b = pkgRetry.NewConstant(cfg.InitialDelay)
 	case Exponential:
-		b = pkgRetry.NewExponential(cfg.InitialDelay)
+		if cfg.Multiplier == 0 || cfg.Multiplier == multiplier {
+			return pkgRetry.NewExponential(cfg.InitialDelay)
+		}
+		return newExponential(cfg.InitialDelay, cfg.Multiplier)
 	case Fibonacci:
-		b = pkgRetry.NewFibonacci(cfg.InitialDelay)
+		return pkgRetry.NewFibonacci(cfg.InitialDelay)
+	case DecorrelatedJitter:
+		return newDecorrelatedJitter(r, cfg.InitialDelay, cfg.MaxDuration)
+	case Linear:
+		return newLinear(cfg.InitialDelay)
+	case Random:
+		cfg.BackoffType = resolveBackoffType(cfg.BackoffType, r)
+		return baseBackoff(cfg, r)
 	default:
-		b = pkgRetry.NewExponential(cfg.InitialDelay)
+		return pkgRetry.NewConstant(cfg.InitialDelay)
+	}
+}
+
+// attemptLimit returns the number of attempts allowed by the backoff limits of cfg, 0 means unlimited
+func attemptLimit(cfg Config) int {
+	switch {
+	case cfg.MaxAttempts > 0 && len(cfg.Schedule) > 0:
+		// the schedule still stops the retry after its last delay
+		return min(cfg.MaxAttempts, len(cfg.Schedule)+1)
+	case cfg.MaxAttempts > 0:
+		return cfg.MaxAttempts
+	case len(cfg.Schedule) > 0:
+		return len(cfg.Schedule) + 1
+	case cfg.Infinite || cfg.MaxRetries < 0:
+		return 0
+	case cfg.MaxRetries > 0:
+		return cfg.MaxRetries + 1
+	default:
+		return maxRetries + 1
+	}
+}
+
+// resolveBackoffType returns the concrete backoff type, Random picks "constant", "exponential" or "fibonacci" with r
+func resolveBackoffType(t BackoffType, r *rand.Rand) BackoffType {
+	if t == Random {
+		return []BackoffType{Constant, Exponential, Fibonacci}[r.Intn(3)]
+	}
+
+	return t
+}
+
+// jitterApplies reports whether the jitter is applied to the backoff type according to JitterOnlyFor, Random must be resolved first
+func jitterApplies(cfg Config) bool {
+	if len(cfg.JitterOnlyFor) == 0 {
+		return true
+	}
+
+	backoffType := cfg.BackoffType
+	if backoffType == "" {
+		backoffType = Constant
+	}
+	for _, v := range cfg.JitterOnlyFor {
+		if v == backoffType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Set config backoff
+func getBackoff(cfg Config) pkgRetry.Backoff {
+	r := getRand(cfg)
+	// the Random choice is made once so the jitter is decided for the concrete type
+	cfg.BackoffType = resolveBackoffType(cfg.BackoffType, r)
+	b := baseBackoff(cfg, r)
+
+	jitterMode := cfg.JitterMode
+	if !jitterApplies(cfg) {
+		jitterMode = JitterNone
+	}
+
+	switch jitterMode {
+	case JitterNone:
+	case JitterFull:
+		b = withFullJitter(r, b)
+	case JitterEqual:
+		b = withEqualJitter(r, b)
+	default:
+		if cfg.Jitter > 0 {
+			b = withJitter(r, cfg.Jitter, cfg.JitterMaxFraction, b)
+		} else if cfg.JitterPercent > 0 {
+			b = withJitterPercent(r, cfg.JitterPercent, b)
+		}
+	}
+
+	if cfg.RandomizeFirstDelay {
+		b = withRandomFirstDelay(r, b)
+	}
+
+	if cfg.MinInterval > 0 {
+		b = withMinDelay(cfg.MinInterval, b)
 	}
 
-	if cfg.Jitter > 0 {
-		b = pkgRetry.WithJitter(cfg.Jitter, b)
+	if cfg.MaxDelay > 0 {
+		b = withMaxDelay(cfg.MaxDelay, b)
 	}
 
 	if cfg.MaxDuration > 0 {
-		b = pkgRetry.WithMaxDuration(cfg.MaxDuration, b)
+		b = withMaxDuration(getClock(cfg), cfg.MaxDuration, b)
 	}
 
-	if cfg.MaxRetries > 0 {
+	switch {
+	case cfg.MaxAttempts > 0:
+		b = pkgRetry.WithMaxRetries(uint64(cfg.MaxAttempts-1), b)
+	case len(cfg.Schedule) > 0:
+		// the schedule stops by itself after the last delay
+	case cfg.Infinite || cfg.MaxRetries < 0:
+		// no limit on the number of retries
+	case cfg.MaxRetries > 0:
 		b = pkgRetry.WithMaxRetries(uint64(cfg.MaxRetries), b)
-	} else if cfg.MaxRetries == 0 {
+	case cfg.MaxRetries == 0:
 		b = pkgRetry.WithMaxRetries(uint64(maxRetries), b)
 	}
 
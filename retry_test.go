@@ -0,0 +1,1592 @@
+package goretry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/harlesbayu/go-retry/goretrytest"
+)
+
+// setDefaultConfig sets the default configuration for the duration of the test
+func setDefaultConfig(t *testing.T, cfg Config) {
+	t.Helper()
+
+	SetDefaultConfig(cfg)
+	t.Cleanup(func() {
+		defaultMu.Lock()
+		defaultCfg = nil
+		defaultMu.Unlock()
+	})
+}
+
+// fastConfig returns a configuration retrying retries times with a 1ms constant delay and no jitter
+func fastConfig(retries int) Config {
+	return Config{InitialDelay: time.Millisecond, MaxRetries: retries}
+}
+
+// failFor returns a function failing with err for the first n calls, the calls are counted in calls
+func failFor(n int, err error, calls *int) func(context.Context) error {
+	return func(context.Context) error {
+		*calls++
+		if *calls <= n {
+			return err
+		}
+
+		return nil
+	}
+}
+
+func TestDoRetryWrappedErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		err  error
+		want bool
+	}{
+		{name: "wrapped", err: fmt.Errorf("open: %w", os.ErrNotExist), want: true},
+		{name: "doubly wrapped", err: fmt.Errorf("load: %w", fmt.Errorf("open: %w", os.ErrNotExist)), want: true},
+		{name: "path error", err: &os.PathError{Op: "open", Path: "x", Err: os.ErrNotExist}, want: true},
+		{name: "same message", err: errors.New(os.ErrNotExist.Error())},
+		{name: "same message MatchByMessage", cfg: Config{MatchByMessage: true}, err: errors.New(os.ErrNotExist.Error()), want: true},
+		{name: "other error", err: os.ErrExist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := fastConfig(2)
+			cfg.MatchByMessage = tt.cfg.MatchByMessage
+
+			calls := 0
+			err := DoRetry(context.Background(), cfg, failFor(1, tt.err, &calls), []error{os.ErrNotExist})
+			if retried := calls == 2; retried != tt.want || (tt.want && err != nil) {
+				t.Errorf("DoRetry() = %v after %d calls, want retried %t", err, calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestDoRetryDisabled(t *testing.T) {
+	errTest := errors.New("test")
+
+	for name, mark := range map[string]func(error) error{
+		"plain":     func(err error) error { return err },
+		"permanent": PermanentError,
+		"reset":     ResetBackoff,
+		"retryable": RetryableError,
+	} {
+		t.Run(name, func(t *testing.T) {
+			clock := goretrytest.NewFakeClock(time.Now())
+			cfg := Config{Disabled: true, InitialDelay: time.Hour, MaxRetries: 100, FirstAttemptDelay: time.Hour, Clock: clock}
+			calls := 0
+			err := DoRetry(context.Background(), cfg, func(context.Context) error {
+				calls++
+				return mark(errTest)
+			}, []error{errTest})
+			if err != errTest || calls != 1 {
+				t.Errorf("DoRetry() = %v after %d calls, want %v after 1", err, calls, errTest)
+			}
+			if sleeps := clock.Sleeps(); len(sleeps) != 0 {
+				t.Errorf("sleeps = %v, want none", sleeps)
+			}
+		})
+	}
+}
+
+func TestDoRetryDefaultMatchByMessage(t *testing.T) {
+	setDefaultConfig(t, Config{InitialDelay: time.Millisecond, MaxRetries: 2, MatchByMessage: true})
+
+	retryableError := []error{errors.New("busy")}
+	fn := func(calls *int) func(context.Context) error {
+		return func(context.Context) error {
+			*calls++
+			return errors.New("busy")
+		}
+	}
+
+	var calls int
+	attempts, err := DoRetryCount(context.Background(), Config{}, fn(&calls), retryableError)
+	if err == nil || attempts != 3 || calls != 3 {
+		t.Errorf("DoRetryCount() = %d, %v after %d calls, want 3 attempts", attempts, err, calls)
+	}
+
+	calls = 0
+	_ = DoRetryWithBudget(context.Background(), Config{}, fn(&calls), retryableError, NewRetryBudget(10, 0))
+	if calls != 3 {
+		t.Errorf("DoRetryWithBudget() calls = %d, want 3", calls)
+	}
+}
+
+func TestDoRetryOnlyListedEmptyList(t *testing.T) {
+	errTest := errors.New("test")
+
+	tests := []struct {
+		name      string
+		onlyList  bool
+		err       error
+		wantCalls int
+	}{
+		{name: "plain error flag off", err: errTest, wantCalls: 1},
+		{name: "plain error flag on", onlyList: true, err: errTest, wantCalls: 1},
+		{name: "marked error flag off", err: RetryableError(errTest), wantCalls: 3},
+		{name: "marked error flag on", onlyList: true, err: RetryableError(errTest), wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{InitialDelay: time.Millisecond, MaxRetries: 2, RetryOnlyListed: tt.onlyList}
+			calls := 0
+			err := DoRetry(context.Background(), cfg, func(context.Context) error {
+				calls++
+				return tt.err
+			}, nil)
+			if !errors.Is(err, errTest) || calls != tt.wantCalls {
+				t.Errorf("DoRetry() = %v after %d calls, want %v after %d", err, calls, errTest, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoRetryBeforeRetry(t *testing.T) {
+	errTest := errors.New("test")
+
+	t.Run("not called after the last attempt", func(t *testing.T) {
+		var attempts []int
+		gaveUp := false
+		cfg := Config{
+			InitialDelay: time.Millisecond,
+			MaxRetries:   2,
+			BeforeRetry: func(_ context.Context, attempt int, _ error) bool {
+				attempts = append(attempts, attempt)
+				return true
+			},
+			OnGiveUp: func(int, error) {
+				gaveUp = true
+			},
+		}
+		err := DoRetry(context.Background(), cfg, func(context.Context) error {
+			return errTest
+		}, []error{errTest})
+
+		var exhausted *RetriesExhaustedError
+		if !errors.As(err, &exhausted) || !gaveUp {
+			t.Errorf("DoRetry() = %v, gave up %t, want *RetriesExhaustedError and OnGiveUp", err, gaveUp)
+		}
+		if len(attempts) != 2 || attempts[0] != 1 || attempts[1] != 2 {
+			t.Errorf("BeforeRetry attempts = %v, want [1 2]", attempts)
+		}
+	})
+
+	t.Run("veto stops the retry", func(t *testing.T) {
+		calls := 0
+		cfg := Config{
+			InitialDelay: time.Millisecond,
+			MaxRetries:   5,
+			BeforeRetry: func(_ context.Context, attempt int, _ error) bool {
+				return attempt < 2
+			},
+		}
+		err := DoRetry(context.Background(), cfg, func(context.Context) error {
+			calls++
+			return errTest
+		}, []error{errTest})
+		if err != errTest || calls != 2 {
+			t.Errorf("DoRetry() = %v after %d calls, want %v after 2", err, calls, errTest)
+		}
+	})
+}
+
+func TestDoRetryEscalateOnRepeat(t *testing.T) {
+	errA := errors.New("a")
+	errB := errors.New("b")
+
+	run := func(seq []error) []time.Duration {
+		var delays []time.Duration
+		cfg := Config{
+			InitialDelay:     time.Millisecond,
+			MaxRetries:       len(seq) - 1,
+			EscalateOnRepeat: true,
+			OnRetry: func(_ int, _ error, delay time.Duration) {
+				delays = append(delays, delay)
+			},
+		}
+		calls := 0
+		_ = DoRetry(context.Background(), cfg, func(context.Context) error {
+			calls++
+			return fmt.Errorf("call %d: %w", calls, seq[calls-1])
+		}, []error{errA, errB})
+
+		return delays
+	}
+
+	want := []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 8 * time.Millisecond}
+	if got := run([]error{errA, errA, errA, errA, errA}); !reflect.DeepEqual(got, want) {
+		t.Errorf("repeated error delays = %v, want %v", got, want)
+	}
+
+	want = []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond}
+	if got := run([]error{errA, errB, errA, errB, errA}); !reflect.DeepEqual(got, want) {
+		t.Errorf("flapping error delays = %v, want %v", got, want)
+	}
+}
+
+func TestDoRetryDeadlineUsesClock(t *testing.T) {
+	deadline := time.Now().Add(time.Hour)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	clock := goretrytest.NewFakeClock(deadline.Add(-3 * time.Second))
+	cfg := Config{InitialDelay: time.Second, MaxRetries: 10, Clock: clock}
+	errTest := errors.New("test")
+
+	calls := 0
+	err := DoRetry(ctx, cfg, func(context.Context) error {
+		calls++
+		return errTest
+	}, []error{errTest})
+	if !errors.Is(err, errTest) || calls != 4 {
+		t.Errorf("DoRetry() = %v after %d calls, want %v after 4", err, calls, errTest)
+	}
+	if got := len(clock.Sleeps()); got != 3 {
+		t.Errorf("sleeps = %d, want 3", got)
+	}
+}
+
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("status %d", e.code)
+}
+
+func TestDoRetryWithTypes(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCalls int
+	}{
+		{name: "custom type", err: &statusError{code: 503}, wantCalls: 2},
+		{name: "wrapped custom type", err: fmt.Errorf("call: %w", &statusError{code: 503}), wantCalls: 2},
+		{name: "other type", err: os.ErrNotExist, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := new(*statusError)
+			calls := 0
+			err := DoRetryWithTypes(context.Background(), fastConfig(2), failFor(1, tt.err, &calls), []any{target})
+			if calls != tt.wantCalls {
+				t.Errorf("DoRetryWithTypes() = %v after %d calls, want %d calls", err, calls, tt.wantCalls)
+			}
+			if tt.wantCalls == 2 && (*target == nil || (*target).code != 503) {
+				t.Errorf("target = %v, want the matched error", *target)
+			}
+		})
+	}
+}
+
+func TestDoRetryWithResult(t *testing.T) {
+	type user struct {
+		ID   int
+		Name string
+	}
+	errTest := errors.New("test")
+
+	t.Run("struct", func(t *testing.T) {
+		calls := 0
+		got, err := DoRetryWithResult(context.Background(), fastConfig(3), func(context.Context) (user, error) {
+			calls++
+			if calls < 3 {
+				return user{ID: -1}, errTest
+			}
+			return user{ID: 1, Name: "alice"}, nil
+		}, []error{errTest})
+		if err != nil || got != (user{ID: 1, Name: "alice"}) {
+			t.Errorf("DoRetryWithResult() = %v, %v, want the user", got, err)
+		}
+	})
+
+	t.Run("pointer", func(t *testing.T) {
+		got, err := DoRetryWithResult(context.Background(), fastConfig(2), func(context.Context) (*user, error) {
+			return &user{ID: 1}, errTest
+		}, []error{errTest})
+		if !errors.Is(err, errTest) || got != nil {
+			t.Errorf("DoRetryWithResult() = %v, %v, want nil, %v", got, err, errTest)
+		}
+	})
+
+	t.Run("non retryable", func(t *testing.T) {
+		calls := 0
+		got, err := DoRetryWithResult(context.Background(), fastConfig(2), func(context.Context) (int, error) {
+			calls++
+			return 42, os.ErrExist
+		}, []error{errTest})
+		if err != os.ErrExist || got != 0 || calls != 1 {
+			t.Errorf("DoRetryWithResult() = %d, %v after %d calls, want 0, %v after 1", got, err, calls, os.ErrExist)
+		}
+	})
+}
+
+func TestDoRetryCount(t *testing.T) {
+	errTest := errors.New("test")
+
+	tests := []struct {
+		name      string
+		failures  int
+		wantCount int
+		wantErr   bool
+	}{
+		{name: "first try", failures: 0, wantCount: 1},
+		{name: "third try", failures: 2, wantCount: 3},
+		{name: "exhausted", failures: 10, wantCount: 4, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			count, err := DoRetryCount(context.Background(), fastConfig(3), failFor(tt.failures, errTest, &calls), []error{errTest})
+			if count != tt.wantCount || calls != tt.wantCount || (err != nil) != tt.wantErr {
+				t.Errorf("DoRetryCount() = %d, %v after %d calls, want %d", count, err, calls, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestDoRetryOnRetry(t *testing.T) {
+	errTest := errors.New("test")
+
+	type call struct {
+		attempt int
+		err     error
+		delay   time.Duration
+	}
+	var got []call
+	cfg := fastConfig(2)
+	cfg.OnRetry = func(attempt int, err error, delay time.Duration) {
+		got = append(got, call{attempt: attempt, err: err, delay: delay})
+	}
+
+	calls := 0
+	_ = DoRetry(context.Background(), cfg, failFor(10, errTest, &calls), []error{errTest})
+
+	want := []call{{1, errTest, time.Millisecond}, {2, errTest, time.Millisecond}}
+	if calls != 3 || !reflect.DeepEqual(got, want) {
+		t.Errorf("OnRetry calls = %v after %d attempts, want %v after 3", got, calls, want)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "default", cfg: DefaultConfig()},
+		{name: "negative InitialDelay", cfg: Config{InitialDelay: -time.Second}, wantErr: true},
+		{name: "zero InitialDelay", cfg: Config{MaxRetries: 1}, wantErr: true},
+		{name: "negative Jitter", cfg: Config{InitialDelay: time.Second, Jitter: -time.Second}, wantErr: true},
+		{name: "negative MaxDuration", cfg: Config{InitialDelay: time.Second, MaxDuration: -time.Second}, wantErr: true},
+		{name: "unknown BackoffType", cfg: Config{InitialDelay: time.Second, BackoffType: "quadratic"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrInvalidConfig)) {
+				t.Errorf("Validate() = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDoRetryInvalidConfig(t *testing.T) {
+	cfg := Config{InitialDelay: time.Second, BackoffType: "quadratic"}
+
+	calls := 0
+	if err := DoRetry(context.Background(), cfg, failFor(0, nil, &calls), nil); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("DoRetry() = %v, want %v", err, ErrInvalidConfig)
+	}
+	if err := DoRetryWithCustomRetryableError(context.Background(), cfg, failFor(0, nil, &calls)); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("DoRetryWithCustomRetryableError() = %v, want %v", err, ErrInvalidConfig)
+	}
+	if calls != 0 {
+		t.Errorf("fn called %d times, want 0", calls)
+	}
+}
+
+func TestConfigApply(t *testing.T) {
+	zero := 0
+	delay := time.Second
+	cfg := DefaultConfig()
+	cfg.Apply(ConfigPatch{MaxRetries: &zero, InitialDelay: &delay})
+	if cfg.MaxRetries != 0 || cfg.InitialDelay != time.Second {
+		t.Errorf("Apply() = %d retries, %s delay, want 0 retries, 1s delay", cfg.MaxRetries, cfg.InitialDelay)
+	}
+	if want := DefaultConfig(); cfg.Jitter != want.Jitter || cfg.MaxDuration != want.MaxDuration {
+		t.Errorf("Apply() changed fields that were not provided: %+v", cfg)
+	}
+
+	cfg = DefaultConfig()
+	cfg.UpdateConfig(Config{MaxRetries: 0, InitialDelay: time.Second})
+	if cfg.MaxRetries != maxRetries || cfg.InitialDelay != time.Second {
+		t.Errorf("UpdateConfig() = %d retries, %s delay, want %d retries, 1s delay", cfg.MaxRetries, cfg.InitialDelay, maxRetries)
+	}
+}
+
+func TestDoRetryIf(t *testing.T) {
+	shouldRetry := func(err error) bool {
+		var statusErr *statusError
+		return errors.As(err, &statusErr) && statusErr.code >= 500
+	}
+
+	tests := []struct {
+		name        string
+		shouldRetry func(error) bool
+		err         error
+		wantCalls   int
+	}{
+		{name: "server error", shouldRetry: shouldRetry, err: &statusError{code: 503}, wantCalls: 2},
+		{name: "client error", shouldRetry: shouldRetry, err: &statusError{code: 404}, wantCalls: 1},
+		{name: "nil predicate", err: os.ErrNotExist, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := DoRetryIf(context.Background(), fastConfig(2), failFor(1, tt.err, &calls), tt.shouldRetry)
+			if calls != tt.wantCalls || (tt.wantCalls == 2) != (err == nil) {
+				t.Errorf("DoRetryIf() = %v after %d calls, want %d calls", err, calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoRetryOnSuccess(t *testing.T) {
+	errTest := errors.New("test")
+
+	tests := []struct {
+		name      string
+		failures  int
+		wantCalls []int
+	}{
+		{name: "first try", failures: 0, wantCalls: []int{1}},
+		{name: "third try", failures: 2, wantCalls: []int{3}},
+		{name: "failure", failures: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			cfg := fastConfig(2)
+			cfg.OnSuccess = func(attempts int, _ time.Duration) {
+				got = append(got, attempts)
+			}
+
+			calls := 0
+			_ = DoRetry(context.Background(), cfg, failFor(tt.failures, errTest, &calls), []error{errTest})
+			if !reflect.DeepEqual(got, tt.wantCalls) {
+				t.Errorf("OnSuccess attempts = %v, want %v", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoRetryWithResultCount(t *testing.T) {
+	errTest := errors.New("test")
+
+	calls := 0
+	got, count, err := DoRetryWithResultCount(context.Background(), fastConfig(3), func(context.Context) (map[string]int, error) {
+		calls++
+		if calls < 2 {
+			return nil, errTest
+		}
+		return map[string]int{"calls": calls}, nil
+	}, []error{errTest})
+	if err != nil || count != 2 || got["calls"] != 2 {
+		t.Errorf("DoRetryWithResultCount() = %v, %d, %v, want the result after 2 attempts", got, count, err)
+	}
+
+	got, count, err = DoRetryWithResultCount(context.Background(), fastConfig(3), func(context.Context) (map[string]int, error) {
+		return map[string]int{}, errTest
+	}, []error{errTest})
+	if !errors.Is(err, errTest) || count != 4 || got != nil {
+		t.Errorf("DoRetryWithResultCount() = %v, %d, %v, want nil after 4 attempts", got, count, err)
+	}
+}
+
+func TestDoRetryOnGiveUp(t *testing.T) {
+	errTest := errors.New("test")
+
+	tests := []struct {
+		name     string
+		fn       func(calls *int) func(context.Context) error
+		wantCall bool
+	}{
+		{name: "exhausted", fn: func(calls *int) func(context.Context) error { return failFor(10, errTest, calls) }, wantCall: true},
+		{name: "success", fn: func(calls *int) func(context.Context) error { return failFor(1, errTest, calls) }},
+		{name: "non retryable", fn: func(calls *int) func(context.Context) error { return failFor(10, os.ErrExist, calls) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			var gotErr error
+			cfg := fastConfig(2)
+			cfg.OnGiveUp = func(attempts int, lastErr error) {
+				got = append(got, attempts)
+				gotErr = lastErr
+			}
+
+			calls := 0
+			_ = DoRetry(context.Background(), cfg, tt.fn(&calls), []error{errTest})
+			if tt.wantCall && (len(got) != 1 || got[0] != 3 || gotErr != errTest) {
+				t.Errorf("OnGiveUp calls = %v with %v, want one call with 3 attempts and %v", got, gotErr, errTest)
+			}
+			if !tt.wantCall && len(got) != 0 {
+				t.Errorf("OnGiveUp calls = %v, want none", got)
+			}
+		})
+	}
+
+	t.Run("max duration", func(t *testing.T) {
+		calls := 0
+		cfg := Config{InitialDelay: time.Second, Infinite: true, MaxDuration: 3 * time.Second, Clock: goretrytest.NewFakeClock(time.Now())}
+		cfg.OnGiveUp = func(int, error) {
+			calls++
+		}
+		_ = DoRetry(context.Background(), cfg, func(context.Context) error {
+			return errTest
+		}, []error{errTest})
+		if calls != 1 {
+			t.Errorf("OnGiveUp calls = %d, want 1", calls)
+		}
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		cfg := fastConfig(5)
+		cfg.OnGiveUp = func(int, error) {
+			calls++
+		}
+		_ = DoRetry(ctx, cfg, func(context.Context) error {
+			cancel()
+			return errTest
+		}, []error{errTest})
+		if calls != 0 {
+			t.Errorf("OnGiveUp calls = %d, want 0", calls)
+		}
+	})
+}
+
+func TestDoRetryFirstAttemptDelay(t *testing.T) {
+	clock := goretrytest.NewFakeClock(time.Now())
+	start := clock.Now()
+	cfg := Config{InitialDelay: time.Second, FirstAttemptDelay: 5 * time.Second, Clock: clock}
+
+	var firstCall time.Time
+	err := DoRetry(context.Background(), cfg, func(context.Context) error {
+		firstCall = clock.Now()
+		return nil
+	}, nil)
+	if err != nil || firstCall.Sub(start) != 5*time.Second {
+		t.Errorf("DoRetry() = %v, first call after %s, want nil after 5s", err, firstCall.Sub(start))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := 0
+	if err := DoRetry(ctx, cfg, failFor(0, nil, &calls), nil); !errors.Is(err, context.Canceled) || calls != 0 {
+		t.Errorf("DoRetry() = %v after %d calls, want %v before any call", err, calls, context.Canceled)
+	}
+}
+
+func TestDoRetryAttemptTimeout(t *testing.T) {
+	cfg := fastConfig(3)
+	cfg.AttemptTimeout = 10 * time.Millisecond
+
+	calls := 0
+	err := DoRetry(context.Background(), cfg, func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}, nil)
+	if err != nil || calls != 3 {
+		t.Errorf("DoRetry() = %v after %d calls, want nil after 3", err, calls)
+	}
+
+	calls = 0
+	err = DoRetry(context.Background(), cfg, func(ctx context.Context) error {
+		calls++
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	}, nil)
+	var exhausted *RetriesExhaustedError
+	if !errors.As(err, &exhausted) || !errors.Is(err, context.DeadlineExceeded) || calls != 4 {
+		t.Errorf("DoRetry() = %v after %d calls, want *RetriesExhaustedError wrapping %v after 4", err, calls, context.DeadlineExceeded)
+	}
+}
+
+func TestDoSimple(t *testing.T) {
+	errTest := errors.New("test")
+
+	calls := 0
+	if err := DoSimple(context.Background(), fastConfig(3), func() error {
+		calls++
+		if calls < 2 {
+			return errTest
+		}
+		return nil
+	}, []error{errTest}); err != nil || calls != 2 {
+		t.Errorf("DoSimple() = %v after %d calls, want nil after 2", err, calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cfg := fastConfig(-1)
+	cfg.InitialDelay = time.Hour
+	calls = 0
+	start := time.Now()
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err := DoSimple(ctx, cfg, func() error {
+		calls++
+		return errTest
+	}, []error{errTest})
+	if !errors.Is(err, context.Canceled) || calls != 1 || time.Since(start) > time.Second {
+		t.Errorf("DoSimple() = %v after %d calls in %s, want %v after 1 call", err, calls, time.Since(start), context.Canceled)
+	}
+}
+
+func TestConfigClone(t *testing.T) {
+	errTest := errors.New("test")
+	base := DefaultConfig()
+	base.SuccessErrors = []error{errTest}
+	base.Schedule = []time.Duration{time.Second}
+	base.JitterOnlyFor = []BackoffType{Exponential}
+
+	clone := base.Clone()
+	clone.UpdateConfig(Config{MaxRetries: 9, InitialDelay: time.Minute})
+	clone.SuccessErrors[0] = os.ErrExist
+	clone.Schedule[0] = time.Minute
+	clone.JitterOnlyFor[0] = Linear
+
+	if base.MaxRetries != maxRetries || base.InitialDelay != initialDelay {
+		t.Errorf("base = %d retries, %s delay, want it unchanged", base.MaxRetries, base.InitialDelay)
+	}
+	if base.SuccessErrors[0] != errTest || base.Schedule[0] != time.Second || base.JitterOnlyFor[0] != Exponential {
+		t.Errorf("base slices = %v %v %v, want them unchanged", base.SuccessErrors, base.Schedule, base.JitterOnlyFor)
+	}
+}
+
+func TestDoRetryWithStop(t *testing.T) {
+	errTest := errors.New("test")
+	cfg := Config{InitialDelay: time.Hour, MaxRetries: 3}
+
+	stop := make(chan struct{})
+	time.AfterFunc(10*time.Millisecond, func() {
+		close(stop)
+	})
+
+	start := time.Now()
+	calls := 0
+	err := DoRetryWithStop(context.Background(), cfg, failFor(10, errTest, &calls), []error{errTest}, stop)
+	if !errors.Is(err, ErrStopped) || calls != 1 {
+		t.Errorf("DoRetryWithStop() = %v after %d calls, want %v after 1", err, calls, ErrStopped)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("DoRetryWithStop() returned after %s, want it to stop during the delay", elapsed)
+	}
+}
+
+func TestDoRetryUseContextDeadline(t *testing.T) {
+	errTest := errors.New("test")
+	deadline := time.Now().Add(time.Hour)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	clock := goretrytest.NewFakeClock(deadline.Add(-2500 * time.Millisecond))
+	cfg := Config{InitialDelay: time.Second, MaxRetries: 10, MaxDuration: time.Minute, UseContextDeadline: true, Clock: clock}
+
+	err := DoRetry(ctx, cfg, func(context.Context) error {
+		return errTest
+	}, []error{errTest})
+
+	var exhausted *RetriesExhaustedError
+	if !errors.As(err, &exhausted) || !errors.Is(err, errTest) {
+		t.Errorf("DoRetry() = %v, want *RetriesExhaustedError wrapping %v", err, errTest)
+	}
+	if want := []time.Duration{time.Second, time.Second}; !reflect.DeepEqual(clock.Sleeps(), want) {
+		t.Errorf("sleeps = %v, want %v", clock.Sleeps(), want)
+	}
+
+	clock = goretrytest.NewFakeClock(deadline.Add(-3 * time.Second))
+	cfg = Config{InitialDelay: 5 * time.Second, MaxRetries: 3, UseContextDeadline: true, Clock: clock}
+	calls := 0
+	err = DoRetry(ctx, cfg, failFor(10, errTest, &calls), []error{errTest})
+	if !errors.As(err, &exhausted) || calls != 1 || len(clock.Sleeps()) != 0 {
+		t.Errorf("DoRetry() = %v after %d calls and sleeps %v, want *RetriesExhaustedError after 1 call without sleeping", err, calls, clock.Sleeps())
+	}
+}
+
+func TestDoRetryCollect(t *testing.T) {
+	errs := []error{errors.New("first"), errors.New("second"), errors.New("third")}
+
+	calls := 0
+	got, err := DoRetryCollect(context.Background(), fastConfig(2), func(context.Context) error {
+		calls++
+		return errs[calls-1]
+	}, errs)
+	if !reflect.DeepEqual(got, errs) || !errors.Is(err, errs[2]) {
+		t.Errorf("DoRetryCollect() = %v, %v, want %v and the last error", got, err, errs)
+	}
+
+	calls = 0
+	got, err = DoRetryCollect(context.Background(), fastConfig(2), failFor(0, nil, &calls), errs)
+	if len(got) != 0 || err != nil {
+		t.Errorf("DoRetryCollect() = %v, %v, want no errors", got, err)
+	}
+}
+
+func TestDoRetryForever(t *testing.T) {
+	errTest := errors.New("test")
+	clock := goretrytest.NewFakeClock(time.Now())
+	cfg := Config{InitialDelay: time.Second, BackoffType: Exponential, MaxRetries: 3, MaxDuration: 10 * time.Second, MaxDelay: 4 * time.Second, Clock: clock}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	err := DoRetryForever(ctx, cfg, func(context.Context) error {
+		calls++
+		if calls == 8 {
+			cancel()
+		}
+		return errTest
+	}, []error{errTest})
+
+	if !errors.Is(err, context.Canceled) || calls != 8 {
+		t.Errorf("DoRetryForever() = %v after %d calls, want %v after 8", err, calls, context.Canceled)
+	}
+	s := time.Second
+	if want := []time.Duration{s, 2 * s, 4 * s, 4 * s, 4 * s, 4 * s, 4 * s}; !reflect.DeepEqual(clock.Sleeps(), want) {
+		t.Errorf("sleeps = %v, want %v", clock.Sleeps(), want)
+	}
+
+	calls = 0
+	if err := DoRetryForever(context.Background(), cfg, failFor(5, errTest, &calls), []error{errTest}); err != nil || calls != 6 {
+		t.Errorf("DoRetryForever() = %v after %d calls, want nil after 6", err, calls)
+	}
+}
+
+func TestDoRetryMaxAttempts(t *testing.T) {
+	errTest := errors.New("test")
+
+	for _, tt := range []struct {
+		maxAttempts int
+		maxRetries  int
+		wantWarn    bool
+	}{
+		{maxAttempts: 1},
+		{maxAttempts: 3, maxRetries: 2},
+		{maxAttempts: 2, maxRetries: 5, wantWarn: true},
+	} {
+		logger := &fakeLogger{}
+		cfg := Config{InitialDelay: time.Millisecond, MaxAttempts: tt.maxAttempts, MaxRetries: tt.maxRetries, Logger: logger}
+
+		calls := 0
+		_ = DoRetry(context.Background(), cfg, failFor(10, errTest, &calls), []error{errTest})
+		if calls != tt.maxAttempts {
+			t.Errorf("MaxAttempts %d calls = %d, want %d", tt.maxAttempts, calls, tt.maxAttempts)
+		}
+
+		warned := len(logger.warn) > 0 && strings.Contains(logger.warn[0], "overrides MaxRetries")
+		if warned != tt.wantWarn {
+			t.Errorf("MaxAttempts %d MaxRetries %d warnings = %q, want warning %t", tt.maxAttempts, tt.maxRetries, logger.warn, tt.wantWarn)
+		}
+	}
+}
+
+func TestDoRetryJoinErrors(t *testing.T) {
+	errFirst := errors.New("first")
+	errLast := errors.New("last")
+	seq := []error{errFirst, errFirst, errLast}
+
+	run := func(join bool) error {
+		cfg := fastConfig(2)
+		cfg.JoinErrors = join
+		calls := 0
+
+		return DoRetry(context.Background(), cfg, func(context.Context) error {
+			calls++
+			return seq[calls-1]
+		}, []error{errFirst, errLast})
+	}
+
+	if err := run(true); !errors.Is(err, errFirst) || !errors.Is(err, errLast) {
+		t.Errorf("DoRetry() joined = %v, want it to match %v and %v", err, errFirst, errLast)
+	}
+	if err := run(false); errors.Is(err, errFirst) || !errors.Is(err, errLast) {
+		t.Errorf("DoRetry() = %v, want only the last error", err)
+	}
+}
+
+func TestDoRetryParentContextExpires(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	cfg := fastConfig(5)
+	cfg.AttemptTimeout = time.Second
+	calls := 0
+	err := DoRetry(ctx, cfg, func(ctx context.Context) error {
+		calls++
+		<-ctx.Done()
+		return ctx.Err()
+	}, []error{context.DeadlineExceeded})
+
+	var ctxErr *ContextError
+	if !errors.As(err, &ctxErr) || !errors.Is(err, context.DeadlineExceeded) || calls != 1 {
+		t.Errorf("DoRetry() = %v after %d calls, want *ContextError wrapping %v after 1", err, calls, context.DeadlineExceeded)
+	}
+}
+
+type temporaryError struct {
+	temporary bool
+}
+
+func (e temporaryError) Error() string {
+	return fmt.Sprintf("temporary %t", e.temporary)
+}
+
+func (e temporaryError) Temporary() bool {
+	return e.temporary
+}
+
+func TestDoRetryTemporary(t *testing.T) {
+	tests := []struct {
+		name      string
+		flag      bool
+		err       error
+		wantCalls int
+	}{
+		{name: "temporary", flag: true, err: temporaryError{temporary: true}, wantCalls: 2},
+		{name: "wrapped temporary", flag: true, err: fmt.Errorf("dial: %w", temporaryError{temporary: true}), wantCalls: 2},
+		{name: "not temporary", flag: true, err: temporaryError{}, wantCalls: 1},
+		{name: "flag off", err: temporaryError{temporary: true}, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := fastConfig(2)
+			cfg.RetryTemporary = tt.flag
+
+			calls := 0
+			_ = DoRetry(context.Background(), cfg, failFor(1, tt.err, &calls), nil)
+			if calls != tt.wantCalls {
+				t.Errorf("DoRetry() calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoRetryNoSleepAfterLastAttempt(t *testing.T) {
+	errTest := errors.New("test")
+	s := time.Second
+
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "max retries", cfg: Config{InitialDelay: s, MaxRetries: 3}},
+		{name: "max attempts", cfg: Config{InitialDelay: s, MaxAttempts: 3}},
+		{name: "schedule", cfg: Config{Schedule: []time.Duration{s, 2 * s}}},
+		{name: "custom backoff", cfg: Config{MaxRetries: 2, CustomBackoff: func(attempt int) time.Duration { return time.Duration(attempt) * s }}},
+		{name: "absolute max calls", cfg: Config{InitialDelay: s, Infinite: true, AbsoluteMaxCalls: 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := goretrytest.NewFakeClock(time.Now())
+			tt.cfg.Clock = clock
+
+			calls := 0
+			err := DoRetry(context.Background(), tt.cfg, failFor(100, errTest, &calls), []error{errTest})
+
+			sleeps := clock.Sleeps()
+			if len(sleeps) != calls-1 {
+				t.Errorf("sleeps = %v after %d calls, want %d sleeps", sleeps, calls, calls-1)
+			}
+
+			var total time.Duration
+			for _, d := range sleeps {
+				total += d
+			}
+			var exhausted *RetriesExhaustedError
+			if !errors.As(err, &exhausted) || exhausted.Elapsed != total {
+				t.Errorf("DoRetry() = %v, want *RetriesExhaustedError with an elapsed time of %s", err, total)
+			}
+		})
+	}
+}
+
+func TestDoRetrySuccessErrors(t *testing.T) {
+	errTest := errors.New("test")
+
+	for _, returnErr := range []bool{false, true} {
+		cfg := fastConfig(5)
+		cfg.SuccessErrors = []error{io.EOF}
+		cfg.ReturnSuccessError = returnErr
+		succeeded := 0
+		cfg.OnSuccess = func(int, time.Duration) {
+			succeeded++
+		}
+
+		calls := 0
+		err := DoRetry(context.Background(), cfg, func(context.Context) error {
+			calls++
+			if calls < 2 {
+				return errTest
+			}
+			return fmt.Errorf("read: %w", io.EOF)
+		}, []error{errTest})
+
+		if calls != 2 || succeeded != 1 {
+			t.Errorf("DoRetry() calls = %d, successes = %d, want 2 calls and 1 success", calls, succeeded)
+		}
+		if got := errors.Is(err, io.EOF); got != returnErr || (!returnErr && err != nil) {
+			t.Errorf("DoRetry() with ReturnSuccessError %t = %v", returnErr, err)
+		}
+	}
+}
+
+func TestSetDefaultConfig(t *testing.T) {
+	errTest := errors.New("test")
+	setDefaultConfig(t, Config{InitialDelay: time.Millisecond, MaxRetries: 1})
+
+	if got := DefaultConfigValue(); got.MaxRetries != 1 || got.InitialDelay != time.Millisecond {
+		t.Errorf("DefaultConfigValue() = %+v, want the configuration set", got)
+	}
+
+	calls := 0
+	if err := DoRetry(context.Background(), Config{}, failFor(10, errTest, &calls), []error{errTest}); !errors.Is(err, errTest) || calls != 2 {
+		t.Errorf("DoRetry() with a zero config = %v after %d calls, want %v after 2", err, calls, errTest)
+	}
+
+	calls = 0
+	if err := DoRetry(context.Background(), fastConfig(3), failFor(10, errTest, &calls), []error{errTest}); calls != 4 {
+		t.Errorf("DoRetry() with a config = %v after %d calls, want 4 calls", err, calls)
+	}
+}
+
+func TestSetDefaultConfigConcurrent(t *testing.T) {
+	setDefaultConfig(t, DefaultConfig())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			SetDefaultConfig(Config{InitialDelay: time.Millisecond, MaxRetries: i})
+			_ = DefaultConfigValue()
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestDoRetrySchedule(t *testing.T) {
+	errTest := errors.New("test")
+	s := time.Second
+	schedule := []time.Duration{s, 3 * s, 10 * s}
+	clock := goretrytest.NewFakeClock(time.Now())
+	cfg := Config{BackoffType: Exponential, MaxRetries: 10, Schedule: schedule, Clock: clock}
+
+	count, err := DoRetryCount(context.Background(), cfg, func(context.Context) error {
+		return errTest
+	}, []error{errTest})
+	if !errors.Is(err, errTest) || count != len(schedule)+1 {
+		t.Errorf("DoRetryCount() = %d, %v, want %d attempts", count, err, len(schedule)+1)
+	}
+	if !reflect.DeepEqual(clock.Sleeps(), schedule) {
+		t.Errorf("sleeps = %v, want %v", clock.Sleeps(), schedule)
+	}
+
+	bad := Config{Schedule: []time.Duration{s, -s}}
+	if err := bad.Validate(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Validate() with a negative delay = %v, want %v", err, ErrInvalidConfig)
+	}
+}
+
+func TestDoRetryArg(t *testing.T) {
+	errTest := errors.New("test")
+
+	var args []int
+	got, err := DoRetryArg(context.Background(), fastConfig(3), 21, func(_ context.Context, n int) (int, error) {
+		args = append(args, n)
+		if len(args) < 3 {
+			return 0, errTest
+		}
+		return n * 2, nil
+	}, []error{errTest})
+	if err != nil || got != 42 {
+		t.Errorf("DoRetryArg() = %d, %v, want 42", got, err)
+	}
+	if want := []int{21, 21, 21}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+}
+
+func TestDoRetryDelayFunc(t *testing.T) {
+	errTest := errors.New("test")
+	clock := goretrytest.NewFakeClock(time.Now())
+
+	var attempts []int
+	cfg := Config{InitialDelay: 1500 * time.Millisecond, MaxRetries: 3, Clock: clock}
+	cfg.DelayFunc = func(attempt int, computed time.Duration) time.Duration {
+		attempts = append(attempts, attempt)
+		return computed.Round(time.Second) + time.Duration(attempt)*time.Millisecond
+	}
+
+	calls := 0
+	_ = DoRetry(context.Background(), cfg, failFor(10, errTest, &calls), []error{errTest})
+
+	want := []time.Duration{2001 * time.Millisecond, 2002 * time.Millisecond, 2003 * time.Millisecond}
+	if !reflect.DeepEqual(clock.Sleeps(), want) {
+		t.Errorf("sleeps = %v, want %v", clock.Sleeps(), want)
+	}
+	if !reflect.DeepEqual(attempts, []int{1, 2, 3}) {
+		t.Errorf("DelayFunc attempts = %v, want [1 2 3]", attempts)
+	}
+}
+
+func TestDoRetryMatch(t *testing.T) {
+	errSentinel := errors.New("sentinel")
+	matchers := []func(error) bool{
+		MatchError(errSentinel),
+		func(err error) bool {
+			return strings.Contains(err.Error(), "connection reset")
+		},
+		nil,
+	}
+
+	tests := []struct {
+		name      string
+		err       error
+		wantCalls int
+	}{
+		{name: "sentinel", err: fmt.Errorf("call: %w", errSentinel), wantCalls: 2},
+		{name: "substring", err: errors.New("read: connection reset by peer"), wantCalls: 2},
+		{name: "no match", err: errors.New("bad request"), wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			_ = DoRetryMatch(context.Background(), fastConfig(2), failFor(1, tt.err, &calls), matchers)
+			if calls != tt.wantCalls {
+				t.Errorf("DoRetryMatch() calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoRetryOnlyListed(t *testing.T) {
+	errTest := errors.New("test")
+	cfg := fastConfig(2)
+	cfg.RetryOnlyListed = true
+	cfg.RetryTemporary = true
+
+	calls := 0
+	if err := DoRetry(context.Background(), cfg, failFor(1, errTest, &calls), []error{errTest}); err != nil || calls != 2 {
+		t.Errorf("DoRetry() listed error = %v after %d calls, want nil after 2", err, calls)
+	}
+
+	calls = 0
+	_ = DoRetry(context.Background(), cfg, failFor(1, temporaryError{temporary: true}, &calls), []error{errTest})
+	if calls != 1 {
+		t.Errorf("DoRetry() temporary error calls = %d, want 1", calls)
+	}
+}
+
+func TestDoRetryAbsoluteMaxCalls(t *testing.T) {
+	errTest := errors.New("test")
+
+	for name, cfg := range map[string]Config{
+		"infinite":     {InitialDelay: time.Millisecond, Infinite: true, AbsoluteMaxCalls: 5},
+		"max retries":  {InitialDelay: time.Millisecond, MaxRetries: 100, AbsoluteMaxCalls: 5},
+		"max attempts": {InitialDelay: time.Millisecond, MaxAttempts: 100, AbsoluteMaxCalls: 5},
+	} {
+		calls := 0
+		err := DoRetry(context.Background(), cfg, failFor(100, errTest, &calls), []error{errTest})
+		var exhausted *RetriesExhaustedError
+		if !errors.As(err, &exhausted) || calls != 5 {
+			t.Errorf("%s: DoRetry() = %v after %d calls, want *RetriesExhaustedError after 5", name, err, calls)
+		}
+	}
+
+	calls := 0
+	cfg := Config{InitialDelay: time.Millisecond, AbsoluteMaxCalls: 5}
+	_ = DoRetryForever(context.Background(), cfg, failFor(100, errTest, &calls), []error{errTest})
+	if calls != 5 {
+		t.Errorf("DoRetryForever() calls = %d, want 5", calls)
+	}
+}
+
+func TestDoRetryZeroInitialDelay(t *testing.T) {
+	errTest := errors.New("test")
+
+	for _, backoffType := range []BackoffType{Constant, Exponential, Fibonacci, Linear, DecorrelatedJitter} {
+		calls := 0
+		cfg := Config{BackoffType: backoffType, MaxRetries: 3}
+		if err := DoRetry(context.Background(), cfg, failFor(10, errTest, &calls), []error{errTest}); !errors.Is(err, ErrInvalidConfig) || calls != 0 {
+			t.Errorf("%s: DoRetry() = %v after %d calls, want %v before any call", backoffType, err, calls, ErrInvalidConfig)
+		}
+	}
+
+	clock := goretrytest.NewFakeClock(time.Now())
+	cfg := Config{Schedule: []time.Duration{0, 0}, MaxDuration: time.Minute, Clock: clock}
+	calls := 0
+	_ = DoRetry(context.Background(), cfg, failFor(10, errTest, &calls), []error{errTest})
+	if want := []time.Duration{0, 0}; calls != 3 || !reflect.DeepEqual(clock.Sleeps(), want) {
+		t.Errorf("zero schedule = %d calls with sleeps %v, want 3 calls with %v", calls, clock.Sleeps(), want)
+	}
+}
+
+func TestDoRetryCustom(t *testing.T) {
+	errTest := errors.New("test")
+
+	tests := []struct {
+		name      string
+		err       error
+		wantErr   error
+		wantCalls int
+	}{
+		{name: "retryable", err: RetryableError(errTest), wantCalls: 2},
+		{name: "wrapped retryable", err: fmt.Errorf("call: %w", RetryableError(errTest)), wantCalls: 2},
+		{name: "reset", err: ResetBackoff(errTest), wantCalls: 2},
+		{name: "permanent", err: PermanentError(errTest), wantErr: errTest, wantCalls: 1},
+		{name: "unmarked", err: errTest, wantErr: errTest, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := DoRetryCustom(context.Background(), fastConfig(2), failFor(1, tt.err, &calls))
+			if err != tt.wantErr || calls != tt.wantCalls {
+				t.Errorf("DoRetryCustom() = %v after %d calls, want %v after %d", err, calls, tt.wantErr, tt.wantCalls)
+			}
+		})
+	}
+
+	for _, onlyListed := range []bool{false, true} {
+		cfg := fastConfig(2)
+		cfg.RetryTemporary = true
+		cfg.RetryOnlyListed = onlyListed
+		want := 3
+		if onlyListed {
+			want = 1
+		}
+
+		calls := 0
+		_ = DoRetryCustom(context.Background(), cfg, failFor(10, temporaryError{temporary: true}, &calls))
+		if calls != want {
+			t.Errorf("DoRetryCustom() with RetryOnlyListed %t = %d calls for a temporary error, want %d", onlyListed, calls, want)
+		}
+	}
+
+	calls := 0
+	err := DoRetryCustom(context.Background(), fastConfig(2), failFor(10, RetryableError(errTest), &calls))
+	var exhausted *RetriesExhaustedError
+	if !errors.As(err, &exhausted) || exhausted.Err != errTest || IsRetryable(err) {
+		t.Errorf("DoRetryCustom() = %v, want *RetriesExhaustedError wrapping the unmarked error", err)
+	}
+}
+
+func TestDoRetryCancelDuringBackoff(t *testing.T) {
+	errTest := errors.New("test")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := Config{InitialDelay: time.Minute, MaxRetries: 3}
+	calls := 0
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	err := DoRetry(ctx, cfg, failFor(10, errTest, &calls), []error{errTest})
+	elapsed := time.Since(start)
+
+	var ctxErr *ContextError
+	if !errors.As(err, &ctxErr) || !errors.Is(err, context.Canceled) || ctxErr.LastErr != errTest || calls != 1 {
+		t.Errorf("DoRetry() = %v after %d calls, want *ContextError wrapping %v after 1", err, calls, context.Canceled)
+	}
+	if elapsed >= time.Second {
+		t.Errorf("DoRetry() returned after %s, want a prompt return well before the %s delay", elapsed, cfg.InitialDelay)
+	}
+}
+
+func TestMergeConfigs(t *testing.T) {
+	base := DefaultConfig()
+	service := Config{MaxRetries: 5, BackoffType: Exponential}
+	call := Config{InitialDelay: time.Second, MaxRetries: 2}
+
+	got := MergeConfigs(base, service, call)
+	if got.MaxRetries != 2 || got.BackoffType != Exponential || got.InitialDelay != time.Second || got.Jitter != base.Jitter {
+		t.Errorf("MergeConfigs() = %d retries, %s, %s delay, %s jitter, want 2 retries, %s, %s delay, %s jitter",
+			got.MaxRetries, got.BackoffType, got.InitialDelay, got.Jitter, Exponential, time.Second, base.Jitter)
+	}
+	if base.MaxRetries != maxRetries || base.BackoffType != DefaultConfig().BackoffType {
+		t.Errorf("base = %d retries, %s, want it unchanged", base.MaxRetries, base.BackoffType)
+	}
+	if got := MergeConfigs(base); !reflect.DeepEqual(got, base) {
+		t.Errorf("MergeConfigs(base) = %+v, want %+v", got, base)
+	}
+}
+
+func TestDoRetryOnFlaky(t *testing.T) {
+	errTest := errors.New("test")
+
+	tests := []struct {
+		name         string
+		failures     int
+		wantFired    bool
+		wantAttempts int
+		wantErrs     []error
+	}{
+		{name: "first try", failures: 0},
+		{name: "third try", failures: 2, wantFired: true, wantAttempts: 3, wantErrs: []error{errTest, errTest}},
+		{name: "failure", failures: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fired := 0
+			var gotAttempts int
+			var gotErrs []error
+			cfg := fastConfig(2)
+			cfg.OnFlaky = func(attempts int, errs []error) {
+				fired++
+				gotAttempts, gotErrs = attempts, errs
+			}
+
+			calls := 0
+			_ = DoRetry(context.Background(), cfg, failFor(tt.failures, errTest, &calls), []error{errTest})
+			if (fired == 1) != tt.wantFired || fired > 1 {
+				t.Fatalf("OnFlaky fired %d times, want fired %t", fired, tt.wantFired)
+			}
+			if gotAttempts != tt.wantAttempts || !reflect.DeepEqual(gotErrs, tt.wantErrs) {
+				t.Errorf("OnFlaky(%d, %v), want OnFlaky(%d, %v)", gotAttempts, gotErrs, tt.wantAttempts, tt.wantErrs)
+			}
+		})
+	}
+}
+
+func TestDoRetryInfinite(t *testing.T) {
+	errTest := errors.New("test")
+
+	tests := map[string]Config{
+		"infinite":      {InitialDelay: time.Second, Infinite: true},
+		"minus one":     {InitialDelay: time.Second, MaxRetries: -1},
+		"infinite wins": {InitialDelay: time.Second, MaxRetries: 2, Infinite: true},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg.Clock = goretrytest.NewFakeClock(time.Now())
+			calls := 0
+			if err := DoRetry(context.Background(), cfg, failFor(100, errTest, &calls), []error{errTest}); err != nil || calls != 101 {
+				t.Errorf("DoRetry() = %v after %d calls, want nil after 101", err, calls)
+			}
+		})
+	}
+
+	err := DoRetry(context.Background(), Config{InitialDelay: time.Second, MaxRetries: -2}, func(context.Context) error {
+		return nil
+	}, nil)
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("DoRetry() with MaxRetries -2 = %v, want %v", err, ErrInvalidConfig)
+	}
+}
+
+func TestDoRetryAdaptiveFunc(t *testing.T) {
+	errTest := errors.New("test")
+	clock := goretrytest.NewFakeClock(time.Now())
+	cfg := Config{
+		InitialDelay: time.Second,
+		MaxRetries:   3,
+		Clock:        clock,
+		AdaptiveFunc: func(lastLatency time.Duration) time.Duration {
+			if lastLatency > time.Second {
+				return lastLatency
+			}
+			return 0
+		},
+	}
+
+	latencies := []time.Duration{10 * time.Millisecond, 2 * time.Second, 10 * time.Millisecond}
+	calls := 0
+	err := DoRetry(context.Background(), cfg, func(context.Context) error {
+		calls++
+		if calls > len(latencies) {
+			return nil
+		}
+		clock.Advance(latencies[calls-1])
+		return errTest
+	}, []error{errTest})
+	if err != nil || calls != 4 {
+		t.Errorf("DoRetry() = %v after %d calls, want nil after 4", err, calls)
+	}
+	s := time.Second
+	if want := []time.Duration{s, 3 * s, s}; !reflect.DeepEqual(clock.Sleeps(), want) {
+		t.Errorf("sleeps = %v, want the slow attempt to lengthen the next delay %v", clock.Sleeps(), want)
+	}
+}
+
+func TestDoRetryAdaptiveFuncLimits(t *testing.T) {
+	errTest := errors.New("test")
+	ms := time.Millisecond
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want []time.Duration
+	}{
+		{name: "max delay and max duration", cfg: Config{InitialDelay: 10 * ms, MaxRetries: 5, MaxDelay: 20 * ms, MaxDuration: 50 * ms}, want: []time.Duration{20 * ms, 20 * ms, 10 * ms}},
+		{name: "max duration", cfg: Config{InitialDelay: 10 * ms, MaxRetries: 5, MaxDuration: 50 * ms}, want: []time.Duration{50 * ms}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := goretrytest.NewFakeClock(time.Now())
+			cfg := tt.cfg
+			cfg.Clock = clock
+			cfg.AdaptiveFunc = func(time.Duration) time.Duration {
+				return 200 * ms
+			}
+
+			calls := 0
+			_ = DoRetry(context.Background(), cfg, failFor(10, errTest, &calls), []error{errTest})
+			if !reflect.DeepEqual(clock.Sleeps(), tt.want) {
+				t.Errorf("sleeps = %v, want %v", clock.Sleeps(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDoRetryCancelable(t *testing.T) {
+	errTest := errors.New("test")
+	cfg := Config{InitialDelay: time.Minute, MaxRetries: 3}
+
+	var calls atomic.Int32
+	done, cancel := DoRetryCancelable(context.Background(), cfg, func(context.Context) error {
+		calls.Add(1)
+		return errTest
+	}, []error{errTest})
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	select {
+	case err := <-done:
+		var ctxErr *ContextError
+		if !errors.As(err, &ctxErr) || !errors.Is(err, ErrStopped) || calls.Load() != 1 {
+			t.Errorf("DoRetryCancelable() = %v after %d calls, want *ContextError wrapping %v after 1", err, calls.Load(), ErrStopped)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("DoRetryCancelable() did not return after cancel")
+	}
+
+	done, cancel = DoRetryCancelable(context.Background(), fastConfig(2), func(context.Context) error {
+		return nil
+	}, nil)
+	defer cancel()
+	if err := <-done; err != nil {
+		t.Errorf("DoRetryCancelable() = %v, want nil", err)
+	}
+}
+
+func TestDoRetryResultIf(t *testing.T) {
+	errTest := errors.New("test")
+	errFatal := errors.New("fatal")
+	retryEmpty := func(v string, err error) bool {
+		return v == "" || err == errTest
+	}
+
+	tests := []struct {
+		name        string
+		results     []string
+		errs        []error
+		shouldRetry func(string, error) bool
+		want        string
+		wantErr     error
+		wantCalls   int
+	}{
+		{name: "empty result", results: []string{"", "", "ok"}, errs: []error{nil, nil, nil}, shouldRetry: retryEmpty, want: "ok", wantCalls: 3},
+		{name: "retryable error", results: []string{"partial", "ok"}, errs: []error{errTest, nil}, shouldRetry: retryEmpty, want: "ok", wantCalls: 2},
+		{name: "rejected error", results: []string{"partial"}, errs: []error{errFatal}, shouldRetry: retryEmpty, wantErr: errFatal, wantCalls: 1},
+		{name: "nil predicate", results: []string{"", "", "ok"}, errs: []error{errTest, nil, nil}, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			got, err := DoRetryResultIf(context.Background(), fastConfig(3), func(context.Context) (string, error) {
+				calls++
+				return tt.results[calls-1], tt.errs[calls-1]
+			}, tt.shouldRetry)
+			if got != tt.want || err != tt.wantErr || calls != tt.wantCalls {
+				t.Errorf("DoRetryResultIf() = %q, %v after %d calls, want %q, %v after %d", got, err, calls, tt.want, tt.wantErr, tt.wantCalls)
+			}
+		})
+	}
+
+	calls := 0
+	_, err := DoRetryResultIf(context.Background(), fastConfig(2), func(context.Context) (string, error) {
+		calls++
+		return "", nil
+	}, retryEmpty)
+	if !errors.Is(err, ErrResultRejected) || calls != 3 {
+		t.Errorf("DoRetryResultIf() = %v after %d calls, want %v after 3", err, calls, ErrResultRejected)
+	}
+}
+
+func TestDoRetryWithFallback(t *testing.T) {
+	errTest := errors.New("test")
+	errFatal := errors.New("fatal")
+	errFallback := errors.New("fallback")
+
+	tests := []struct {
+		name          string
+		err           error
+		fallbackErr   error
+		want          string
+		wantFallbacks int
+	}{
+		{name: "fallback succeeds", err: errTest, want: "fallback", wantFallbacks: 1},
+		{name: "fallback fails", err: errTest, fallbackErr: errFallback, wantFallbacks: 1},
+		{name: "non retryable", err: errFatal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, fallbacks := 0, 0
+			got, err := DoRetryWithFallback(context.Background(), fastConfig(2), func(context.Context) (string, error) {
+				calls++
+				return "", tt.err
+			}, func(context.Context) (string, error) {
+				fallbacks++
+				if tt.fallbackErr != nil {
+					return "", tt.fallbackErr
+				}
+				return "fallback", nil
+			}, []error{errTest})
+
+			if got != tt.want || fallbacks != tt.wantFallbacks {
+				t.Errorf("DoRetryWithFallback() = %q after %d fallbacks, want %q after %d", got, fallbacks, tt.want, tt.wantFallbacks)
+			}
+			switch {
+			case tt.err == errFatal:
+				if err != errFatal || calls != 1 {
+					t.Errorf("DoRetryWithFallback() = %v after %d calls, want %v after 1", err, calls, errFatal)
+				}
+			case tt.fallbackErr != nil:
+				var exhausted *RetriesExhaustedError
+				if !errors.As(err, &exhausted) || !errors.Is(err, errFallback) {
+					t.Errorf("DoRetryWithFallback() = %v, want the exhausted error joined with %v", err, errFallback)
+				}
+			default:
+				if err != nil || calls != 3 {
+					t.Errorf("DoRetryWithFallback() = %v after %d calls, want nil after 3", err, calls)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultConfigFor(t *testing.T) {
+	tests := []struct {
+		backoffType BackoffType
+		want        time.Duration
+	}{
+		{backoffType: Constant, want: 3 * time.Second},
+		{backoffType: Linear, want: time.Second},
+		{backoffType: Exponential, want: 500 * time.Millisecond},
+		{backoffType: Fibonacci, want: 500 * time.Millisecond},
+		{backoffType: DecorrelatedJitter, want: 500 * time.Millisecond},
+		{backoffType: Random, want: 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfigFor(tt.backoffType)
+		if cfg.InitialDelay != tt.want || cfg.BackoffType != tt.backoffType {
+			t.Errorf("DefaultConfigFor(%s) = %s %s, want %s %s", tt.backoffType, cfg.BackoffType, cfg.InitialDelay, tt.backoffType, tt.want)
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("DefaultConfigFor(%s).Validate() = %v, want nil", tt.backoffType, err)
+		}
+	}
+
+	if DefaultConfigFor(Exponential).InitialDelay == DefaultConfigFor(Constant).InitialDelay {
+		t.Errorf("DefaultConfigFor(%s) InitialDelay = DefaultConfigFor(%s) InitialDelay, want them to differ", Exponential, Constant)
+	}
+	if err := DefaultConfigFor("unknown").Validate(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("DefaultConfigFor(unknown).Validate() = %v, want %v", err, ErrInvalidConfig)
+	}
+}
+
+func TestDoRetryOnRetryDelayIsSlept(t *testing.T) {
+	errTest := errors.New("test")
+	s := time.Second
+
+	tests := []struct {
+		name string
+		cfg  Config
+		err  error
+	}{
+		{name: "additive jitter", cfg: Config{InitialDelay: s, BackoffType: Exponential, MaxRetries: 4, Jitter: 500 * time.Millisecond}},
+		{name: "full jitter", cfg: Config{InitialDelay: s, BackoffType: Exponential, MaxRetries: 4, JitterMode: JitterFull}},
+		{name: "percent jitter", cfg: Config{InitialDelay: s, BackoffType: Fibonacci, MaxRetries: 4, JitterPercent: 20}},
+		{name: "decorrelated", cfg: Config{InitialDelay: s, BackoffType: DecorrelatedJitter, MaxRetries: 4}},
+		{name: "max duration", cfg: Config{InitialDelay: s, BackoffType: Exponential, MaxRetries: 4, Jitter: 500 * time.Millisecond, MaxDuration: 5 * s}},
+		{
+			name: "delay func",
+			cfg: Config{InitialDelay: s, MaxRetries: 4, Jitter: 500 * time.Millisecond, DelayFunc: func(attempt int, next time.Duration) time.Duration {
+				return next * time.Duration(attempt)
+			}},
+		},
+		{name: "retry after", cfg: Config{InitialDelay: s, MaxRetries: 4, Jitter: 500 * time.Millisecond}, err: retryAfterError{delay: 3 * s}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := goretrytest.NewFakeClock(time.Now())
+			cfg := tt.cfg
+			cfg.Clock = clock
+			cfg.RandSource = rand.New(rand.NewSource(1))
+			var reported []time.Duration
+			cfg.OnRetry = func(_ int, _ error, delay time.Duration) {
+				reported = append(reported, delay)
+			}
+
+			err := tt.err
+			if err == nil {
+				err = errTest
+			}
+			calls := 0
+			_ = DoRetry(context.Background(), cfg, failFor(10, err, &calls), []error{err})
+			if len(reported) == 0 || !reflect.DeepEqual(reported, clock.Sleeps()) {
+				t.Errorf("OnRetry delays = %v, want the sleeps %v", reported, clock.Sleeps())
+			}
+		})
+	}
+
+	const tolerance = 50 * time.Millisecond
+	cfg := Config{InitialDelay: 20 * time.Millisecond, BackoffType: Exponential, MaxRetries: 3, JitterMode: JitterFull, RandSource: rand.New(rand.NewSource(1))}
+	var (
+		delay    time.Duration
+		notified time.Time
+	)
+	cfg.OnRetry = func(_ int, _ error, d time.Duration) {
+		delay, notified = d, time.Now()
+	}
+	calls := 0
+	_ = DoRetry(context.Background(), cfg, func(context.Context) error {
+		calls++
+		if calls > 1 {
+			if slept := time.Since(notified); slept < delay || slept > delay+tolerance {
+				t.Errorf("attempt %d slept %s, want the reported delay %s within %s", calls, slept, delay, tolerance)
+			}
+		}
+		return errTest
+	}, []error{errTest})
+}
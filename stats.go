@@ -0,0 +1,61 @@
+package goretry
+
+import (
+	"context"
+	"time"
+)
+
+// RetryStats describes how the time of a retry was spent, Delays holds the delay before every retry in order including the jitter
+type RetryStats struct {
+	Attempts     int
+	TotalElapsed time.Duration
+	TotalSleep   time.Duration
+	TotalWork    time.Duration
+	Delays       []time.Duration
+}
+
+// statsClock records the time spent sleeping by the wrapped clock
+type statsClock struct {
+	Clock
+	stats *RetryStats
+}
+
+func (c statsClock) Sleep(ctx context.Context, d time.Duration) error {
+	start := c.Now()
+	err := c.Clock.Sleep(ctx, d)
+	c.stats.TotalSleep += c.Now().Sub(start)
+
+	return err
+}
+
+// DoRetryTimed will perform a retry like DoRetry and return the time spent in fn and sleeping between attempts, measured with the configured clock
+func DoRetryTimed(ctx context.Context, cfg Config, fn func(context.Context) error, retryableError []error) (RetryStats, error) {
+	var stats RetryStats
+
+	cfg = resolveConfig(cfg)
+	clock := statsClock{Clock: getClock(cfg), stats: &stats}
+	cfg.Clock = clock
+
+	onRetry := cfg.OnRetry
+	cfg.OnRetry = func(attempt int, err error, nextDelay time.Duration) {
+		stats.Delays = append(stats.Delays, nextDelay)
+		if onRetry != nil {
+			onRetry(attempt, err, nextDelay)
+		}
+	}
+
+	start := clock.Now()
+	attempts, err := DoRetryCount(ctx, cfg, func(ctx context.Context) error {
+		workStart := clock.Now()
+		defer func() {
+			stats.TotalWork += clock.Now().Sub(workStart)
+		}()
+
+		return fn(ctx)
+	}, retryableError)
+
+	stats.Attempts = attempts
+	stats.TotalElapsed = clock.Now().Sub(start)
+
+	return stats, err
+}
@@ -0,0 +1,70 @@
+package goretry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/harlesbayu/go-retry/goretrytest"
+)
+
+func TestDoRetryTimed(t *testing.T) {
+	errTest := errors.New("test")
+	clock := goretrytest.NewFakeClock(time.Now())
+	cfg := Config{InitialDelay: time.Second, BackoffType: Exponential, MaxRetries: 3, Clock: clock}
+
+	calls := 0
+	stats, err := DoRetryTimed(context.Background(), cfg, func(context.Context) error {
+		calls++
+		clock.Advance(100 * time.Millisecond)
+		if calls < 3 {
+			return errTest
+		}
+		return nil
+	}, []error{errTest})
+	if err != nil {
+		t.Fatalf("DoRetryTimed() error = %v", err)
+	}
+
+	want := RetryStats{
+		Attempts:     3,
+		TotalElapsed: 3300 * time.Millisecond,
+		TotalSleep:   3 * time.Second,
+		TotalWork:    300 * time.Millisecond,
+		Delays:       []time.Duration{time.Second, 2 * time.Second},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("DoRetryTimed() = %+v, want %+v", stats, want)
+	}
+	if stats.TotalSleep+stats.TotalWork != stats.TotalElapsed {
+		t.Errorf("sleep %s + work %s, want the elapsed time %s", stats.TotalSleep, stats.TotalWork, stats.TotalElapsed)
+	}
+}
+
+func TestDoRetryTimedDelays(t *testing.T) {
+	errTest := errors.New("test")
+
+	for _, failures := range []int{0, 2, 10} {
+		clock := goretrytest.NewFakeClock(time.Now())
+		cfg := Config{
+			InitialDelay: time.Second,
+			BackoffType:  Exponential,
+			MaxRetries:   4,
+			Jitter:       500 * time.Millisecond,
+			RandSource:   rand.New(rand.NewSource(1)),
+			Clock:        clock,
+		}
+
+		calls := 0
+		stats, _ := DoRetryTimed(context.Background(), cfg, failFor(failures, errTest, &calls), []error{errTest})
+		if len(stats.Delays) != stats.Attempts-1 {
+			t.Errorf("DoRetryTimed() after %d failures = %d delays for %d attempts, want %d", failures, len(stats.Delays), stats.Attempts, stats.Attempts-1)
+		}
+		if sleeps := clock.Sleeps(); len(sleeps) > 0 && !reflect.DeepEqual(stats.Delays, sleeps) {
+			t.Errorf("DoRetryTimed() delays = %v, want the jittered sleeps %v", stats.Delays, sleeps)
+		}
+	}
+}
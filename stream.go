@@ -0,0 +1,40 @@
+package goretry
+
+import "context"
+
+// page is a value returned by the next func of DoRetryStream
+type page[T any] struct {
+	value T
+	ok    bool
+}
+
+/*
+DoRetryStream will fetch the pages with next until it reports false and pass every page to handle, every call of next is retried like DoRetryWithResult
+
+Notes:
+  - The backoff restarts for every page, a page fetched successfully resets the delays, MaxRetries and MaxDuration
+  - The value returned with false is not passed to handle
+  - The error of handle stops the stream immediately and is returned unwrapped
+  - FirstAttemptDelay is only waited before the first page
+  - Every call of next is a separate retry, the hooks such as OnSuccess, OnGiveUp and MetricsHook and the logger report every call on its own
+*/
+func DoRetryStream[T any](ctx context.Context, cfg Config, next func(context.Context) (T, bool, error), handle func(T) error, retryableError []error) error {
+	cfg = resolveConfig(cfg)
+	for {
+		p, err := DoRetryWithResult(ctx, cfg, func(ctx context.Context) (page[T], error) {
+			v, ok, err := next(ctx)
+			return page[T]{value: v, ok: ok}, err
+		}, retryableError)
+		if err != nil {
+			return err
+		}
+		if !p.ok {
+			return nil
+		}
+
+		if err := handle(p.value); err != nil {
+			return err
+		}
+		cfg.FirstAttemptDelay = 0
+	}
+}
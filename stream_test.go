@@ -0,0 +1,102 @@
+package goretry
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/harlesbayu/go-retry/goretrytest"
+)
+
+// paginator returns pages, failing with err failures times before every page
+func paginator(pages []int, failures int, err error, calls *int) func(context.Context) (int, bool, error) {
+	failed := 0
+	return func(context.Context) (int, bool, error) {
+		*calls++
+		if failed < failures {
+			failed++
+			return 0, false, err
+		}
+		failed = 0
+
+		if len(pages) == 0 {
+			return 0, false, nil
+		}
+		v := pages[0]
+		pages = pages[1:]
+		return v, true, nil
+	}
+}
+
+func TestDoRetryStream(t *testing.T) {
+	errTest := errors.New("test")
+	clock := goretrytest.NewFakeClock(time.Now())
+	cfg := Config{InitialDelay: time.Second, BackoffType: Exponential, MaxRetries: 2, Clock: clock}
+
+	var got []int
+	calls := 0
+	err := DoRetryStream(context.Background(), cfg, paginator([]int{1, 2, 3}, 2, errTest, &calls), func(v int) error {
+		got = append(got, v)
+		return nil
+	}, []error{errTest})
+	if err != nil || calls != 12 {
+		t.Errorf("DoRetryStream() = %v after %d calls, want nil after 12", err, calls)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("handled pages = %v, want %v", got, want)
+	}
+	s := time.Second
+	if want := []time.Duration{s, 2 * s, s, 2 * s, s, 2 * s, s, 2 * s}; !reflect.DeepEqual(clock.Sleeps(), want) {
+		t.Errorf("sleeps = %v, want the backoff to restart for every page %v", clock.Sleeps(), want)
+	}
+}
+
+func TestDoRetryStreamErrors(t *testing.T) {
+	errTest := errors.New("test")
+	errHandle := errors.New("handle")
+
+	calls := 0
+	err := DoRetryStream(context.Background(), fastConfig(2), paginator([]int{1, 2}, 3, errTest, &calls), func(int) error {
+		return nil
+	}, []error{errTest})
+	var exhausted *RetriesExhaustedError
+	if !errors.As(err, &exhausted) || calls != 3 {
+		t.Errorf("DoRetryStream() = %v after %d calls, want *RetriesExhaustedError after 3", err, calls)
+	}
+
+	calls = 0
+	var got []int
+	err = DoRetryStream(context.Background(), fastConfig(2), paginator([]int{1, 2}, 0, errTest, &calls), func(v int) error {
+		got = append(got, v)
+		return errHandle
+	}, []error{errTest})
+	if err != errHandle || !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("DoRetryStream() = %v after pages %v, want %v after [1]", err, got, errHandle)
+	}
+}
+
+func TestDoRetryStreamFirstAttemptDelay(t *testing.T) {
+	errTest := errors.New("test")
+	clock := goretrytest.NewFakeClock(time.Now())
+	cfg := Config{InitialDelay: time.Second, MaxRetries: 2, FirstAttemptDelay: 50 * time.Millisecond, Clock: clock}
+
+	var successes int
+	cfg.OnSuccess = func(int, time.Duration) {
+		successes++
+	}
+	calls := 0
+	err := DoRetryStream(context.Background(), cfg, paginator([]int{1, 2, 3, 4, 5}, 0, errTest, &calls), func(int) error {
+		return nil
+	}, []error{errTest})
+	if err != nil || calls != 6 {
+		t.Errorf("DoRetryStream() = %v after %d calls, want nil after 6", err, calls)
+	}
+	if want := []time.Duration{50 * time.Millisecond}; !reflect.DeepEqual(clock.Sleeps(), want) {
+		t.Errorf("sleeps = %v, want FirstAttemptDelay only before the first page %v", clock.Sleeps(), want)
+	}
+	if successes != 6 {
+		t.Errorf("OnSuccess calls = %d, want one for each of the 6 calls of next", successes)
+	}
+}
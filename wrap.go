@@ -0,0 +1,27 @@
+package goretry
+
+import "context"
+
+// Wrap returns a decorator turning fn into a function that performs a retry like DoRetry every time it is called
+func Wrap(cfg Config, retryableError []error) func(func(context.Context) error) func(context.Context) error {
+	cfg = cfg.Clone()
+	retryableError = append([]error(nil), retryableError...)
+
+	return func(fn func(context.Context) error) func(context.Context) error {
+		return func(ctx context.Context) error {
+			return DoRetry(ctx, cfg, fn, retryableError)
+		}
+	}
+}
+
+// WrapResult returns a decorator turning fn into a function that performs a retry like DoRetryWithResult every time it is called
+func WrapResult[T any](cfg Config, retryableError []error) func(func(context.Context) (T, error)) func(context.Context) (T, error) {
+	cfg = cfg.Clone()
+	retryableError = append([]error(nil), retryableError...)
+
+	return func(fn func(context.Context) (T, error)) func(context.Context) (T, error) {
+		return func(ctx context.Context) (T, error) {
+			return DoRetryWithResult(ctx, cfg, fn, retryableError)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package goretry
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	errTest := errors.New("test")
+	cfg := fastConfig(2)
+	retry := Wrap(cfg, []error{errTest})
+	cfg.MaxRetries = 10
+
+	calls := 0
+	fn := retry(failFor(10, errTest, &calls))
+	if err := fn(context.Background()); !errors.Is(err, errTest) || calls != 3 {
+		t.Errorf("wrapped fn = %v after %d calls, want %v after 3", err, calls, errTest)
+	}
+
+	calls = 0
+	fn = retry(failFor(1, errTest, &calls))
+	if err := fn(context.Background()); err != nil || calls != 2 {
+		t.Errorf("wrapped fn = %v after %d calls, want nil after 2", err, calls)
+	}
+}
+
+func TestWrapResult(t *testing.T) {
+	type user struct {
+		Name string
+	}
+	errTest := errors.New("test")
+
+	calls := 0
+	fetch := WrapResult[user](fastConfig(3), []error{errTest})(func(context.Context) (user, error) {
+		calls++
+		if calls <= 2 {
+			return user{}, errTest
+		}
+		return user{Name: "alice"}, nil
+	})
+
+	got, err := fetch(context.Background())
+	if err != nil || got.Name != "alice" || calls != 3 {
+		t.Errorf("wrapped fn = %+v, %v after %d calls, want alice after 3", got, err, calls)
+	}
+}